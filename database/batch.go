@@ -0,0 +1,445 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/convert"
+	"xorm.io/xorm/schemas"
+)
+
+const (
+	OracleMaxBindVars         = 65535 // Oracle 单条语句绑定变量上限
+	OracleMaxInsertAllColumns = 999   // Oracle INSERT ALL 目标列总数上限，即每批行数 × 列数
+)
+
+// Sessioner 可创建带 context 的会话：*xorm.Engine、*xorm.EngineGroup，或事务中的 *xorm.Session
+type Sessioner interface {
+	Context(ctx context.Context) *xorm.Session
+}
+
+// BatchOptions 批量写入选项
+type BatchOptions struct {
+	BatchSize       int  // 每批最多行数，默认 500
+	MaxBindVars     int  // 单条语句绑定变量上限，默认 OracleMaxBindVars；每批行数 × 列数不超过该值
+	ContinueOnError bool // 某批失败后继续写入后续批次，默认遇错即停
+}
+
+func (o BatchOptions) withDefaults() BatchOptions {
+	if o.BatchSize <= 0 {
+		o.BatchSize = 500
+	}
+	if o.MaxBindVars <= 0 {
+		o.MaxBindVars = OracleMaxBindVars
+	}
+	return o
+}
+
+// chunkSize 每批行数，同时受 BatchSize 与绑定变量上限约束
+func (o BatchOptions) chunkSize(columns int) int {
+	return max(1, min(o.BatchSize, o.MaxBindVars/max(columns, 1)))
+}
+
+// insertChunkSize 多行插入的每批行数，Oracle / 达梦的 INSERT ALL 还受目标列总数上限约束
+func (o BatchOptions) insertChunkSize(dbType schemas.DBType, columns int) int {
+	size := o.chunkSize(columns)
+	if dbType == schemas.ORACLE || dbType == schemas.DAMENG {
+		size = max(1, min(size, OracleMaxInsertAllColumns/max(columns, 1)))
+	}
+	return size
+}
+
+// ChunkError 单批写入失败
+type ChunkError struct {
+	Index  int   // 批次序号，从 0 开始
+	Offset int   // 该批首行在输入切片中的下标
+	Count  int   // 该批行数
+	Err    error // 已经 Classify 归类的错误
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("chunk %d (rows %d-%d): %v", e.Index, e.Offset, e.Offset+e.Count-1, e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError 批量写入中失败的批次，可用 errors.Is 判断各批错误类型（如 ErrDuplicateKey）
+type BatchError struct {
+	Chunks []*ChunkError // 失败的批次
+	Total  int           // 总批次数
+}
+
+func (e *BatchError) Error() string {
+	msgs := make([]string, len(e.Chunks))
+	for i, c := range e.Chunks {
+		msgs[i] = c.Error()
+	}
+	return fmt.Sprintf("database: %d of %d chunks failed: %s", len(e.Chunks), e.Total, strings.Join(msgs, "; "))
+}
+
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Chunks))
+	for i, c := range e.Chunks {
+		errs[i] = c
+	}
+	return errs
+}
+
+// BatchInsert 分批多行插入。T 为结构体或结构体指针；Oracle 下每批生成一条 INSERT ALL 语句，
+// 每批行数 × 列数不超过 OracleMaxInsertAllColumns。
+// 返回成功插入的行数；有批次失败时返回 *BatchError，未设置 ContinueOnError 时在首个失败批次处停止
+func BatchInsert[T any](ctx context.Context, db Sessioner, items []T, opts BatchOptions) (int64, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+	opts = opts.withDefaults()
+	s := db.Context(ctx)
+	table, err := s.Engine().TableInfo(items[0])
+	if err != nil {
+		return 0, err
+	}
+	size := opts.insertChunkSize(s.Engine().Dialect().URI().DBType, len(table.ColumnsSeq()))
+	return runChunks(ctx, db, s, len(items), size, opts.ContinueOnError, func(s *xorm.Session, offset, end int) (int64, error) {
+		chunk := items[offset:end]
+		return s.Insert(&chunk)
+	})
+}
+
+// runChunks 按批执行 exec，汇总影响行数与失败批次。首批复用 first 会话
+func runChunks(ctx context.Context, db Sessioner, first *xorm.Session, n, size int, continueOnError bool,
+	exec func(s *xorm.Session, offset, end int) (int64, error)) (int64, error) {
+	var (
+		total    int64
+		batchErr = &BatchError{Total: (n + size - 1) / size}
+	)
+	for index, offset := 0, 0; offset < n; index, offset = index+1, offset+size {
+		s := first
+		if index > 0 {
+			s = db.Context(ctx)
+		}
+		end := min(offset+size, n)
+		affected, err := exec(s, offset, end)
+		if err != nil {
+			batchErr.Chunks = append(batchErr.Chunks, &ChunkError{Index: index, Offset: offset, Count: end - offset, Err: Classify(err)})
+			if !continueOnError || ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		total += affected
+	}
+	if len(batchErr.Chunks) > 0 {
+		return total, batchErr
+	}
+	return total, nil
+}
+
+// UpsertOptions 批量 upsert 选项
+type UpsertOptions struct {
+	BatchOptions
+	KeyColumns    []string // 匹配列，默认主键
+	UpdateColumns []string // 匹配时更新的列，默认除匹配列、自增列与 created 列外的全部列
+	InsertOnly    bool     // 只插入不存在的行，匹配时不更新
+}
+
+// upsertPlan 由结构体 tag 解析出的 upsert 列信息
+type upsertPlan struct {
+	table  *schemas.Table
+	keys   []string
+	insert []*schemas.Column
+	update []string
+}
+
+func newUpsertPlan(table *schemas.Table, opts UpsertOptions) (*upsertPlan, error) {
+	plan := &upsertPlan{table: table, keys: slices.Clone(opts.KeyColumns)}
+	if len(plan.keys) == 0 {
+		plan.keys = slices.Clone(table.PrimaryKeys)
+	}
+	if len(plan.keys) == 0 {
+		return nil, fmt.Errorf("database: upsert %s requires key columns or a primary key", table.Name)
+	}
+	for i, key := range plan.keys {
+		col := table.GetColumn(key)
+		if col == nil {
+			return nil, fmt.Errorf("database: key column %s is not a field of %s", key, table.Name)
+		}
+		plan.keys[i] = col.Name
+	}
+
+	isKey := func(name string) bool { return slices.Contains(plan.keys, name) }
+	for _, col := range table.Columns() {
+		if len(col.FieldIndex) == 0 || (col.IsAutoIncrement && !isKey(col.Name)) {
+			continue
+		}
+		plan.insert = append(plan.insert, col)
+	}
+
+	switch {
+	case opts.InsertOnly:
+	case len(opts.UpdateColumns) > 0:
+		for _, name := range opts.UpdateColumns {
+			col := table.GetColumn(name)
+			if col == nil || !slices.Contains(plan.insert, col) {
+				return nil, fmt.Errorf("database: update column %s is not an insert column of %s", name, table.Name)
+			}
+			plan.update = append(plan.update, col.Name)
+		}
+	default:
+		for _, col := range plan.insert {
+			if !isKey(col.Name) && !col.IsCreated {
+				plan.update = append(plan.update, col.Name)
+			}
+		}
+	}
+	return plan, nil
+}
+
+// sql 生成 rows 行的 upsert 语句，占位符为 ?，由 xorm 按方言转换（Oracle 为 :N）
+func (p *upsertPlan) sql(dbType schemas.DBType, quote func(string) string, rows int) (string, error) {
+	columns := make([]string, len(p.insert))
+	for i, col := range p.insert {
+		columns[i] = quote(col.Name)
+	}
+	table := quote(p.table.Name)
+	var b strings.Builder
+
+	switch dbType {
+	case schemas.ORACLE, schemas.DAMENG:
+		// MERGE INTO t d USING (SELECT ? c1, ? c2 FROM DUAL UNION ALL ...) s ON (d.k = s.k) ...
+		fmt.Fprintf(&b, "MERGE INTO %s d USING (", table)
+		for r := range rows {
+			if r > 0 {
+				b.WriteString(" UNION ALL ")
+			}
+			b.WriteString("SELECT ")
+			for i, c := range columns {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				if r == 0 {
+					b.WriteString("? " + c)
+				} else {
+					b.WriteString("?")
+				}
+			}
+			b.WriteString(" FROM DUAL")
+		}
+		b.WriteString(") s ON (")
+		for i, key := range p.keys {
+			if i > 0 {
+				b.WriteString(" AND ")
+			}
+			fmt.Fprintf(&b, "d.%s = s.%s", quote(key), quote(key))
+		}
+		b.WriteString(")")
+		if len(p.update) > 0 {
+			b.WriteString(" WHEN MATCHED THEN UPDATE SET ")
+			for i, name := range p.update {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				fmt.Fprintf(&b, "d.%s = s.%s", quote(name), quote(name))
+			}
+		}
+		values := make([]string, len(columns))
+		for i, c := range columns {
+			values[i] = "s." + c
+		}
+		fmt.Fprintf(&b, " WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)", strings.Join(columns, ", "), strings.Join(values, ", "))
+
+	case schemas.SQLITE, schemas.POSTGRES, schemas.MYSQL:
+		fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", table, strings.Join(columns, ", "))
+		row := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+		for r := range rows {
+			if r > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(row)
+		}
+		if dbType == schemas.MYSQL {
+			b.WriteString(" ON DUPLICATE KEY UPDATE ")
+			if len(p.update) == 0 {
+				fmt.Fprintf(&b, "%s = %s", quote(p.keys[0]), quote(p.keys[0]))
+			}
+			for i, name := range p.update {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				fmt.Fprintf(&b, "%s = VALUES(%s)", quote(name), quote(name))
+			}
+			break
+		}
+		keys := make([]string, len(p.keys))
+		for i, key := range p.keys {
+			keys[i] = quote(key)
+		}
+		fmt.Fprintf(&b, " ON CONFLICT (%s) DO ", strings.Join(keys, ", "))
+		if len(p.update) == 0 {
+			b.WriteString("NOTHING")
+		}
+		for i, name := range p.update {
+			if i == 0 {
+				b.WriteString("UPDATE SET ")
+			} else {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "%s = excluded.%s", quote(name), quote(name))
+		}
+
+	default:
+		return "", fmt.Errorf("database: upsert is not supported for %s", dbType)
+	}
+	return b.String(), nil
+}
+
+// args 按插入列顺序提取各行的绑定参数
+func (p *upsertPlan) args(engine *xorm.Engine, items reflect.Value) ([]any, error) {
+	now := time.Now()
+	dbType := engine.Dialect().URI().DBType
+	args := make([]any, 0, items.Len()*len(p.insert))
+	for i := range items.Len() {
+		bean := reflect.Indirect(items.Index(i))
+		for _, col := range p.insert {
+			v, err := columnValue(engine, dbType, col, bean, now)
+			if err != nil {
+				return nil, fmt.Errorf("database: column %s: %w", col.Name, err)
+			}
+			args = append(args, v)
+		}
+	}
+	return args, nil
+}
+
+// columnValue 将字段值转换为绑定参数：created/updated 零值取当前时间，JSON 字段序列化，
+// 支持 driver.Valuer 与 xorm convert.ConversionTo
+func columnValue(engine *xorm.Engine, dbType schemas.DBType, col *schemas.Column, bean reflect.Value, now time.Time) (any, error) {
+	field, err := col.ValueOfV(&bean)
+	if err != nil {
+		return nil, err
+	}
+	if (col.IsCreated || col.IsUpdated) && field.IsZero() {
+		switch field.Interface().(type) {
+		case time.Time:
+			return formatTime(engine, dbType, now), nil
+		case int64, int:
+			return now.Unix(), nil
+		}
+	}
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return nil, nil
+		}
+		elem := field.Elem()
+		field = &elem
+	}
+	value := field.Interface()
+	if col.IsJSON {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+	switch v := value.(type) {
+	case driver.Valuer:
+		return v.Value()
+	case convert.ConversionTo:
+		data, err := v.ToDB()
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	case time.Time:
+		return formatTime(engine, dbType, v), nil
+	case bool:
+		if dbType == schemas.ORACLE || dbType == schemas.DAMENG {
+			if v {
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	if field.CanAddr() {
+		if conv, ok := field.Addr().Interface().(convert.ConversionTo); ok {
+			data, err := conv.ToDB()
+			if err != nil {
+				return nil, err
+			}
+			return string(data), nil
+		}
+	}
+	return value, nil
+}
+
+// formatTime 与 xorm 一致转换到数据库时区；SQLite 以文本存储时间
+func formatTime(engine *xorm.Engine, dbType schemas.DBType, t time.Time) any {
+	t = t.In(engine.DatabaseTZ)
+	if dbType == schemas.SQLITE {
+		return t.Format(time.DateTime)
+	}
+	return t
+}
+
+// UpsertSQL 返回 rows 行 upsert 的 SQL，便于查看生成的 MERGE INTO 语句
+func UpsertSQL(engine *xorm.Engine, bean any, rows int, opts UpsertOptions) (string, error) {
+	table, err := engine.TableInfo(bean)
+	if err != nil {
+		return "", err
+	}
+	plan, err := newUpsertPlan(table, opts)
+	if err != nil {
+		return "", err
+	}
+	return plan.sql(engine.Dialect().URI().DBType, engine.Quote, rows)
+}
+
+// Upsert 分批插入或更新。Oracle 下生成 MERGE INTO ... USING (SELECT ... FROM DUAL UNION ALL ...)，
+// SQLite / PostgreSQL 使用 ON CONFLICT，MySQL 使用 ON DUPLICATE KEY UPDATE。
+// 同一批次内匹配列不能重复（Oracle 会报 ORA-30926）。返回值为数据库报告的影响行数，错误处理同 BatchInsert
+func Upsert[T any](ctx context.Context, db Sessioner, items []T, opts UpsertOptions) (int64, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+	opts.BatchOptions = opts.BatchOptions.withDefaults()
+	s := db.Context(ctx)
+	engine := s.Engine()
+	table, err := engine.TableInfo(items[0])
+	if err != nil {
+		return 0, err
+	}
+	plan, err := newUpsertPlan(table, opts)
+	if err != nil {
+		return 0, err
+	}
+	dbType := engine.Dialect().URI().DBType
+	if _, err := plan.sql(dbType, engine.Quote, 1); err != nil {
+		return 0, err
+	}
+
+	size := opts.chunkSize(len(plan.insert))
+	values := reflect.ValueOf(items)
+	return runChunks(ctx, db, s, len(items), size, opts.ContinueOnError, func(s *xorm.Session, offset, end int) (int64, error) {
+		query, err := plan.sql(dbType, engine.Quote, end-offset)
+		if err != nil {
+			return 0, err
+		}
+		args, err := plan.args(engine, values.Slice(offset, end))
+		if err != nil {
+			return 0, err
+		}
+		result, err := s.Exec(append([]any{query}, args...)...)
+		if err != nil {
+			return 0, err
+		}
+		return result.RowsAffected()
+	})
+}
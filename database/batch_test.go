@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+type tradeRecord struct {
+	Id      int64     `xorm:"pk"`
+	Symbol  string    `xorm:"varchar(20) notnull"`
+	Qty     int64     `xorm:"notnull"`
+	Created time.Time `xorm:"created"`
+	Updated time.Time `xorm:"updated"`
+}
+
+func newTrades(from, n int) []*tradeRecord {
+	trades := make([]*tradeRecord, n)
+	for i := range trades {
+		trades[i] = &tradeRecord{Id: int64(from + i), Symbol: "AU", Qty: int64(from + i)}
+	}
+	return trades
+}
+
+func TestBatchInsert_Chunks(t *testing.T) {
+	engine := newSQLiteEngine(t)
+	if err := engine.Sync(new(tradeRecord)); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// 5 列、绑定变量上限 20：每批最多 4 行
+	if size := (BatchOptions{BatchSize: 100, MaxBindVars: 20}).chunkSize(5); size != 4 {
+		t.Fatalf("每批行数=%d want=4", size)
+	}
+	// Oracle INSERT ALL 目标列总数不超过 999：10 列每批最多 99 行，超过 999 列时每批 1 行
+	opts := BatchOptions{}.withDefaults()
+	for _, c := range []struct {
+		dbType  schemas.DBType
+		columns int
+		want    int
+	}{
+		{schemas.ORACLE, 10, 99},
+		{schemas.DAMENG, 10, 99},
+		{schemas.ORACLE, 1200, 1},
+		{schemas.SQLITE, 10, 500},
+	} {
+		if size := opts.insertChunkSize(c.dbType, c.columns); size != c.want {
+			t.Errorf("%s %d 列每批行数=%d want=%d", c.dbType, c.columns, size, c.want)
+		}
+	}
+	n, err := BatchInsert(ctx, engine, newTrades(1, 1050), BatchOptions{BatchSize: 100})
+	if err != nil || n != 1050 {
+		t.Fatalf("BatchInsert=%d %v", n, err)
+	}
+	if count, _ := engine.Count(new(tradeRecord)); count != 1050 {
+		t.Errorf("count=%d", count)
+	}
+
+	// 每批 10 行，第 2、3 批含与已有数据冲突的主键
+	trades := append(newTrades(2001, 10), newTrades(1, 1)...)
+	trades = append(trades, newTrades(3001, 10)...)
+	trades = append(trades, newTrades(2, 1)...)
+	n, err = BatchInsert(ctx, engine, trades, BatchOptions{BatchSize: 10, ContinueOnError: true})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Chunks) != 2 || batchErr.Total != 3 {
+		t.Fatalf("应返回两个失败批次: %v", err)
+	}
+	if c := batchErr.Chunks[0]; c.Index != 1 || c.Offset != 10 || c.Count != 10 {
+		t.Errorf("失败批次信息不符: %+v", c)
+	}
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("批次错误应可归类为 ErrDuplicateKey: %v", err)
+	}
+	if n != 10 {
+		t.Errorf("成功行数=%d want=10", n)
+	}
+
+	// 默认遇错即停
+	_, err = BatchInsert(ctx, engine, append(newTrades(1, 1), newTrades(4001, 1)...), BatchOptions{BatchSize: 1})
+	if !errors.As(err, &batchErr) || len(batchErr.Chunks) != 1 {
+		t.Fatalf("应在首个失败批次停止: %v", err)
+	}
+	if has, _ := engine.ID(4001).Exist(new(tradeRecord)); has {
+		t.Error("失败批次之后的数据不应写入")
+	}
+}
+
+func TestUpsert_SQLite(t *testing.T) {
+	engine := newSQLiteEngine(t)
+	if err := engine.Sync(new(tradeRecord)); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	repo := NewRepository[tradeRecord](engine)
+	if _, err := repo.InsertBatch(ctx, newTrades(1, 3), BatchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	trades := newTrades(2, 3) // 2、3 已存在，4 为新记录
+	for _, tr := range trades {
+		tr.Qty *= 100
+	}
+	if _, err := repo.Upsert(ctx, trades, UpsertOptions{BatchOptions: BatchOptions{BatchSize: 2}}); err != nil {
+		t.Fatalf("Upsert 失败: %v", err)
+	}
+	items, _ := repo.Find(ctx, NewFilter().Asc("id"))
+	want := []int64{1, 200, 300, 400}
+	if len(items) != len(want) {
+		t.Fatalf("记录数=%d", len(items))
+	}
+	for i, it := range items {
+		if it.Qty != want[i] {
+			t.Errorf("id=%d qty=%d want=%d", it.Id, it.Qty, want[i])
+		}
+		if it.Updated.IsZero() {
+			t.Errorf("id=%d updated 应自动填充", it.Id)
+		}
+	}
+
+	trades[0].Qty = -1
+	err := WithTx(ctx, engine, func(s *xorm.Session) error {
+		_, err := Upsert(ctx, s, trades[:1], UpsertOptions{InsertOnly: true})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repo.Get(ctx, 2); got.Qty != 200 {
+		t.Errorf("InsertOnly 不应更新已有记录: %d", got.Qty)
+	}
+}
+
+func TestUpsertSQL_Oracle(t *testing.T) {
+	engine, err := xorm.NewEngine("godror", "user/pass@localhost:1521/orcl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	sql, err := UpsertSQL(engine, new(tradeRecord), 2, UpsertOptions{UpdateColumns: []string{"qty", "updated"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `MERGE INTO "trade_record" d USING (` +
+		`SELECT ? "id", ? "symbol", ? "qty", ? "created", ? "updated" FROM DUAL UNION ALL SELECT ?, ?, ?, ?, ? FROM DUAL) s ` +
+		`ON (d."id" = s."id") WHEN MATCHED THEN UPDATE SET d."qty" = s."qty", d."updated" = s."updated" ` +
+		`WHEN NOT MATCHED THEN INSERT ("id", "symbol", "qty", "created", "updated") ` +
+		`VALUES (s."id", s."symbol", s."qty", s."created", s."updated")`
+	if sql != want {
+		t.Errorf("MERGE 语句不符:\n got=%s\nwant=%s", sql, want)
+	}
+
+	if _, err := UpsertSQL(engine, new(tradeRecord), 1, UpsertOptions{KeyColumns: []string{"no_such"}}); err == nil {
+		t.Error("不存在的匹配列应返回错误")
+	}
+}
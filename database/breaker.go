@@ -0,0 +1,287 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/contexts"
+)
+
+// ErrCircuitOpen 熔断器打开，请求被拒绝
+var ErrCircuitOpen = errors.New("circuit breaker open: 数据库连接异常")
+
+// BreakerState 熔断器状态
+type BreakerState int32
+
+const (
+	StateClosed   BreakerState = iota // 关闭：正常放行
+	StateOpen                         // 打开：拒绝请求，冷却结束后进入半开
+	StateHalfOpen                     // 半开：放行少量试探请求，成功则关闭，失败则重新打开
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerConfig 熔断器配置
+type BreakerConfig struct {
+	FailureThreshold    int                         // 连续失败次数达到该值时打开，0 表示不按连续失败判断；与 FailureRate 均为 0 时按 1 处理
+	FailureRate         float64                     // 窗口内失败率（0~1）达到该值时打开，0 表示不按失败率判断
+	MinRequests         int                         // 按失败率判断所需的窗口内最少请求数，默认 20
+	Window              time.Duration               // 失败率统计窗口，默认 1 分钟
+	Cooldown            time.Duration               // 打开后进入半开前的冷却时间，默认 30 秒
+	HalfOpenMaxRequests int                         // 半开状态允许的试探请求数，全部成功后关闭，默认 1
+	IsFailure           func(err error) bool        // 判断错误是否计入失败，默认 IsDatabaseFailure
+	OnStateChange       func(from, to BreakerState) // 状态变化回调，在锁外同步调用
+}
+
+// CircuitBreaker 熔断器，状态机：closed → open → half-open → closed/open
+type CircuitBreaker struct {
+	config BreakerConfig
+
+	mutex       sync.Mutex
+	state       BreakerState
+	generation  uint64 // 每次状态变化加一，忽略旧状态下请求的结果
+	openedAt    time.Time
+	consecutive int // 连续失败次数
+	windowStart time.Time
+	requests    int
+	failures    int
+	trials      int // 半开状态已放行的试探请求数
+	successes   int // 半开状态试探成功数
+}
+
+// NewCircuitBreaker 创建按连续失败次数打开的熔断器，threshold 不大于 1 时首次失败即打开
+func NewCircuitBreaker(threshold int32) *CircuitBreaker {
+	return NewCircuitBreakerWithConfig(BreakerConfig{FailureThreshold: max(int(threshold), 1)})
+}
+
+// NewCircuitBreakerWithConfig 按配置创建熔断器
+func NewCircuitBreakerWithConfig(config BreakerConfig) *CircuitBreaker {
+	// 未配置任何打开条件时保持首次失败即打开，避免熔断器静默失效
+	if config.FailureThreshold <= 0 && config.FailureRate <= 0 {
+		config.FailureThreshold = 1
+	}
+	if config.MinRequests <= 0 {
+		config.MinRequests = 20
+	}
+	if config.Window <= 0 {
+		config.Window = time.Minute
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = 30 * time.Second
+	}
+	if config.HalfOpenMaxRequests <= 0 {
+		config.HalfOpenMaxRequests = 1
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = IsDatabaseFailure
+	}
+	return &CircuitBreaker{config: config, windowStart: time.Now()}
+}
+
+// State 当前状态，打开且冷却结束时返回半开
+func (b *CircuitBreaker) State() BreakerState {
+	b.mutex.Lock()
+	from, to, changed := b.refreshLocked(time.Now())
+	state := b.state
+	b.mutex.Unlock()
+	b.notify(from, to, changed)
+	return state
+}
+
+// IsOpen 是否处于打开状态
+func (b *CircuitBreaker) IsOpen() bool {
+	return b.State() == StateOpen
+}
+
+// Allow 请求前检查：打开时返回 ErrCircuitOpen，半开时占用一个试探名额
+func (b *CircuitBreaker) Allow() error {
+	_, err := b.allow()
+	return err
+}
+
+// Check 同 Allow
+func (b *CircuitBreaker) Check() error {
+	return b.Allow()
+}
+
+// Fail 记录一次失败
+func (b *CircuitBreaker) Fail() {
+	b.record(b.currentGeneration(), true)
+}
+
+// Success 记录一次成功，打开状态下忽略
+func (b *CircuitBreaker) Success() {
+	b.record(b.currentGeneration(), false)
+}
+
+// Execute 经熔断器执行 fn，按 IsFailure 记录结果
+func (b *CircuitBreaker) Execute(fn func() error) error {
+	gen, err := b.allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	b.record(gen, b.config.IsFailure(err))
+	return err
+}
+
+func (b *CircuitBreaker) currentGeneration() uint64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.generation
+}
+
+func (b *CircuitBreaker) allow() (uint64, error) {
+	b.mutex.Lock()
+	from, to, changed := b.refreshLocked(time.Now())
+	gen := b.generation
+	var err error
+	switch b.state {
+	case StateOpen:
+		err = ErrCircuitOpen
+	case StateHalfOpen:
+		if b.trials >= b.config.HalfOpenMaxRequests {
+			err = ErrCircuitOpen
+		} else {
+			b.trials++
+		}
+	}
+	b.mutex.Unlock()
+	b.notify(from, to, changed)
+	return gen, err
+}
+
+// record 记录 gen 状态下放行的请求结果
+func (b *CircuitBreaker) record(gen uint64, failed bool) {
+	now := time.Now()
+	b.mutex.Lock()
+	from, to, changed := b.refreshLocked(now)
+	if gen != b.generation {
+		b.mutex.Unlock()
+		b.notify(from, to, changed)
+		return
+	}
+	switch b.state {
+	case StateClosed:
+		if now.Sub(b.windowStart) >= b.config.Window {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		b.requests++
+		if failed {
+			b.failures++
+			b.consecutive++
+		} else {
+			b.consecutive = 0
+		}
+		if failed && b.shouldOpenLocked() {
+			from, to, changed = b.setStateLocked(StateOpen, now)
+		}
+	case StateHalfOpen:
+		if failed {
+			from, to, changed = b.setStateLocked(StateOpen, now)
+			break
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenMaxRequests {
+			from, to, changed = b.setStateLocked(StateClosed, now)
+		}
+	}
+	b.mutex.Unlock()
+	b.notify(from, to, changed)
+}
+
+func (b *CircuitBreaker) shouldOpenLocked() bool {
+	if b.config.FailureThreshold > 0 && b.consecutive >= b.config.FailureThreshold {
+		return true
+	}
+	return b.config.FailureRate > 0 && b.requests >= b.config.MinRequests &&
+		float64(b.failures)/float64(b.requests) >= b.config.FailureRate
+}
+
+// refreshLocked 冷却结束时由打开转为半开
+func (b *CircuitBreaker) refreshLocked(now time.Time) (BreakerState, BreakerState, bool) {
+	if b.state == StateOpen && now.Sub(b.openedAt) >= b.config.Cooldown {
+		return b.setStateLocked(StateHalfOpen, now)
+	}
+	return b.state, b.state, false
+}
+
+func (b *CircuitBreaker) setStateLocked(state BreakerState, now time.Time) (BreakerState, BreakerState, bool) {
+	from := b.state
+	b.state = state
+	b.generation++
+	b.trials, b.successes = 0, 0
+	switch state {
+	case StateOpen:
+		b.openedAt = now
+	case StateClosed:
+		b.consecutive, b.requests, b.failures = 0, 0, 0
+		b.windowStart = now
+	}
+	return from, state, true
+}
+
+func (b *CircuitBreaker) notify(from, to BreakerState, changed bool) {
+	if changed && b.config.OnStateChange != nil {
+		b.config.OnStateChange(from, to)
+	}
+}
+
+// ctxKeyBreakerGeneration 在 hook 上下文中保存放行时的状态版本
+type ctxKeyBreakerGeneration struct{}
+
+// breakerHook xorm hook：每条 SQL 执行前检查熔断器，执行后记录结果
+type breakerHook struct {
+	breaker *CircuitBreaker
+}
+
+// NewBreakerHook 创建熔断 hook，COMMIT/ROLLBACK 不受熔断限制以便已开始的事务正常结束
+func NewBreakerHook(breaker *CircuitBreaker) contexts.Hook {
+	return &breakerHook{breaker: breaker}
+}
+
+func (h *breakerHook) BeforeProcess(c *contexts.ContextHook) (context.Context, error) {
+	if isTxEnd(c.SQL) {
+		return c.Ctx, nil
+	}
+	gen, err := h.breaker.allow()
+	if err != nil {
+		return c.Ctx, err
+	}
+	// xorm 存在多个 hook 时只采用最后一个返回的 ctx，写回 c.Ctx 以便后续 hook 在此基础上派生
+	c.Ctx = context.WithValue(c.Ctx, ctxKeyBreakerGeneration{}, gen)
+	return c.Ctx, nil
+}
+
+func (h *breakerHook) AfterProcess(c *contexts.ContextHook) error {
+	// 事务内 ctx 继承自 BEGIN，COMMIT/ROLLBACK 不能重复记录 BEGIN 的结果
+	if isTxEnd(c.SQL) {
+		return nil
+	}
+	if gen, ok := c.Ctx.Value(ctxKeyBreakerGeneration{}).(uint64); ok {
+		h.breaker.record(gen, h.breaker.config.IsFailure(c.Err))
+	}
+	return nil
+}
+
+func isTxEnd(sql string) bool {
+	return sql == "COMMIT" || sql == "ROLLBACK"
+}
+
+// UseCircuitBreaker 为引擎安装熔断 hook，之后所有 SQL 均经熔断器放行
+func UseCircuitBreaker(engine *xorm.Engine, breaker *CircuitBreaker) {
+	engine.AddHook(NewBreakerHook(breaker))
+}
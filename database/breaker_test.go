@@ -0,0 +1,141 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_StateMachine(t *testing.T) {
+	var changes []string
+	b := NewCircuitBreakerWithConfig(BreakerConfig{
+		FailureThreshold: 2,
+		Cooldown:         20 * time.Millisecond,
+		OnStateChange: func(from, to BreakerState) {
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+	})
+
+	b.Fail()
+	if b.State() != StateClosed {
+		t.Fatal("未达到阈值不应打开")
+	}
+	b.Fail()
+	if !b.IsOpen() || !errors.Is(b.Allow(), ErrCircuitOpen) {
+		t.Fatal("连续失败达到阈值应打开并拒绝请求")
+	}
+	b.Success()
+	if !b.IsOpen() {
+		t.Fatal("打开状态下 Success 应被忽略")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("冷却结束后应放行试探请求: %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("半开状态试探名额用尽后应拒绝")
+	}
+	b.Fail()
+	if !b.IsOpen() {
+		t.Fatal("试探失败应重新打开")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if err := b.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("试探请求失败: %v", err)
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("试探成功后应关闭, state=%v", b.State())
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(changes) != len(want) {
+		t.Fatalf("状态变化=%v want=%v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("状态变化[%d]=%s want=%s", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestCircuitBreaker_FailureRate(t *testing.T) {
+	b := NewCircuitBreakerWithConfig(BreakerConfig{FailureRate: 0.5, MinRequests: 4, Window: time.Minute})
+	fail := errors.New("ORA-03113")
+	b.Execute(func() error { return fail })
+	b.Execute(func() error { return nil })
+	b.Execute(func() error { return fail })
+	if b.State() != StateClosed {
+		t.Fatal("请求数不足时不应按失败率打开")
+	}
+	b.Execute(func() error { return sql.ErrNoRows })
+	if b.State() != StateClosed {
+		t.Fatal("sql.ErrNoRows 不应计入失败")
+	}
+	b.Execute(func() error { return fail })
+	if !b.IsOpen() {
+		t.Fatal("失败率达到阈值应打开")
+	}
+}
+
+func TestCircuitBreaker_StaleResultIgnored(t *testing.T) {
+	b := NewCircuitBreakerWithConfig(BreakerConfig{FailureThreshold: 1, Cooldown: time.Hour})
+	gen, err := b.allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Fail()
+	b.record(gen, false)
+	if !b.IsOpen() {
+		t.Fatal("打开前放行的请求结果不应影响新状态")
+	}
+}
+
+func TestCircuitBreaker_HookGatesQueries(t *testing.T) {
+	engine := newSQLiteEngine(t)
+	b := NewCircuitBreakerWithConfig(BreakerConfig{
+		FailureThreshold: 2,
+		Cooldown:         time.Hour,
+		IsFailure:        func(err error) bool { return err != nil }, // SQLite 无法模拟连接故障，任意错误计入
+	})
+	UseCircuitBreaker(engine, b)
+
+	if _, err := engine.Exec("SELECT * FROM no_such_table"); err == nil {
+		t.Fatal("查询不存在的表应失败")
+	}
+	if _, err := engine.Exec("SELECT * FROM no_such_table"); err == nil {
+		t.Fatal("查询不存在的表应失败")
+	}
+	if !b.IsOpen() {
+		t.Fatal("连续 SQL 失败后熔断器应打开")
+	}
+	if _, err := engine.Exec("SELECT 1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("熔断打开时 SQL 应被拒绝: %v", err)
+	}
+}
+
+func TestCircuitBreaker_ZeroThreshold(t *testing.T) {
+	for _, b := range []*CircuitBreaker{NewCircuitBreaker(0), NewCircuitBreakerWithConfig(BreakerConfig{})} {
+		b.Fail()
+		if !b.IsOpen() {
+			t.Error("阈值为 0 时应与旧版本一致，首次失败即打开")
+		}
+	}
+}
+
+func TestCircuitBreaker_HookIgnoresSQLErrors(t *testing.T) {
+	engine := newSQLiteEngine(t)
+	b := NewCircuitBreakerWithConfig(BreakerConfig{FailureThreshold: 1, Cooldown: time.Hour})
+	UseCircuitBreaker(engine, b)
+
+	for range 3 {
+		if _, err := engine.Exec("SELECT * FROM no_such_table"); err == nil {
+			t.Fatal("查询不存在的表应失败")
+		}
+	}
+	if b.State() != StateClosed {
+		t.Fatal("SQL 错误不应触发熔断")
+	}
+}
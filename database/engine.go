@@ -0,0 +1,131 @@
+package database
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"xorm.io/xorm"
+	"xorm.io/xorm/log"
+)
+
+// Config 数据库引擎配置，与具体数据库无关
+// 除 oracle（本包已导入 godror）外，使用其他数据库需由调用方导入对应驱动，例如：
+//
+//	import _ "github.com/go-sql-driver/mysql"   // Driver: mysql
+//	import _ "github.com/lib/pq"                // Driver: postgres
+//	import _ "modernc.org/sqlite"               // Driver: sqlite
+type Config struct {
+	Driver  string `mapstructure:"driver"` // 驱动名：oracle / mysql / postgres / sqlite / sqlite3 等
+	DSN     string `mapstructure:"dsn"`    // 数据源
+	Options `mapstructure:",squash"`
+
+	Logger  *zap.Logger       `mapstructure:"-"` // 日志，为 nil 时输出到标准输出
+	Metrics *SQLMetrics       `mapstructure:"-"` // SQL 统计，为 nil 时若设置了慢 SQL 阈值与 Logger 则仅输出慢 SQL 日志
+	Breaker *CircuitBreaker   `mapstructure:"-"` // 熔断器，为 nil 时不启用熔断与连接监控
+	Tracer  SpanExporter      `mapstructure:"-"` // SQL 链路追踪，为 nil 时不输出 span 事件
+	MaskArg func(arg any) any `mapstructure:"-"` // SQL 日志、慢 SQL 日志与 span 中的参数脱敏，默认 MaskArg
+}
+
+// NewEngine 按配置创建数据库引擎，支持 zap.Logger 注入、连接监控、慢 SQL 统计、熔断
+func NewEngine(cfg Config) (*xorm.Engine, error) {
+	engine, opts, err := openEngine(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// 健康检查
+	if err := engine.Ping(); err != nil {
+		for i := 0; i < opts.PingRetries; i++ {
+			time.Sleep(opts.PingInterval)
+			if err = engine.Ping(); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			engine.Close()
+			return nil, err
+		}
+	}
+
+	setupEngine(engine, cfg)
+	return engine, nil
+}
+
+// setupEngine 安装熔断、连接监控与链路追踪；连接监控在引擎 Close 后退出
+func setupEngine(engine *xorm.Engine, cfg Config) {
+	// 连接监控与熔断，所有 SQL 经熔断器放行
+	if cfg.Breaker != nil {
+		UseCircuitBreaker(engine, cfg.Breaker)
+		go monitorConnection(engine, cfg.Breaker, cfg.Logger, monitorInterval)
+	}
+	// 链路追踪安装在熔断之后，被熔断拒绝的 SQL 不产生 span
+	if cfg.Tracer != nil {
+		engine.AddHook(&tracingHook{exporter: cfg.Tracer, mask: cfg.MaskArg})
+	}
+}
+
+// openEngine 创建引擎并设置连接池与日志，不检查连接
+func openEngine(cfg Config) (*xorm.Engine, Options, error) {
+	if cfg.Driver == "" {
+		return nil, Options{}, errors.New("database: driver is empty")
+	}
+	opts := cfg.Options.withDefaults()
+	if cfg.MaskArg == nil {
+		cfg.MaskArg = MaskArg
+	}
+	level, err := ParseLogLevel(opts.LogLevel)
+	if err != nil {
+		return nil, opts, err
+	}
+
+	engine, err := xorm.NewEngine(cfg.Driver, cfg.DSN)
+	if err != nil {
+		return nil, opts, err
+	}
+	// 连接池配置
+	engine.SetMaxOpenConns(opts.MaxOpenConns)
+	engine.SetMaxIdleConns(opts.MaxIdleConns)
+	engine.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	if opts.ConnMaxIdleTime > 0 {
+		engine.DB().SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	}
+
+	// 日志对接 zap，慢 SQL 统计
+	if cfg.Logger != nil {
+		engine.SetLogger(&ZapXormLogger{logger: cfg.Logger, mask: cfg.MaskArg})
+	} else {
+		engine.SetLogger(log.NewSimpleLogger(os.Stdout))
+	}
+	engine.SetLogLevel(level)
+	engine.ShowSQL(opts.ShowSQL)
+
+	// SQL 统计与慢 SQL 日志
+	if cfg.Metrics != nil {
+		UseMetrics(engine, cfg.Metrics)
+	} else if opts.SlowThreshold > 0 && cfg.Logger != nil {
+		UseMetrics(engine, NewSQLMetrics(MetricsConfig{SlowThreshold: opts.SlowThreshold, MaskArg: cfg.MaskArg, Logger: cfg.Logger}))
+	}
+	return engine, opts, nil
+}
+
+// TryReconnectWithConfig 按创建引擎时的配置尝试断线重连，新引擎保留连接池、日志、慢 SQL、熔断与链路追踪设置
+// 旧引擎关闭后其连接监控随之退出
+func TryReconnectWithConfig(engine **xorm.Engine, cfg Config, maxRetry int) error {
+	var err error
+	for range maxRetry {
+		if *engine != nil {
+			(*engine).Close()
+		}
+		*engine, _, err = openEngine(cfg)
+		if err == nil {
+			setupEngine(*engine, cfg)
+			if err = (*engine).Ping(); err == nil {
+				return nil
+			}
+		}
+		time.Sleep(time.Second * 2)
+	}
+	return err
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	_ "modernc.org/sqlite"
+	"xorm.io/xorm"
+)
+
+// newSQLiteEngine 创建基于临时 SQLite 文件的引擎，无需外部数据库
+func newSQLiteEngine(t *testing.T) *xorm.Engine {
+	t.Helper()
+	engine, err := NewEngine(Config{
+		Driver: "sqlite",
+		DSN:    filepath.Join(t.TempDir(), "test.db"),
+		Logger: zap.NewNop(),
+	})
+	if err != nil {
+		t.Fatalf("创建 SQLite 引擎失败: %v", err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	return engine
+}
+
+func TestNewEngine_SQLiteCRUD(t *testing.T) {
+	engine := newSQLiteEngine(t)
+	if err := AutoMigrate(engine); err != nil {
+		t.Fatalf("自动建表失败: %v", err)
+	}
+
+	user := &User{Name: "张三", Age: 20}
+	if _, err := engine.Insert(user); err != nil {
+		t.Fatalf("插入失败: %v", err)
+	}
+
+	var got User
+	has, err := engine.ID(user.Id).Get(&got)
+	if err != nil || !has {
+		t.Fatalf("查询失败: has=%v err=%v", has, err)
+	}
+	if got.Name != user.Name || got.Age != user.Age {
+		t.Errorf("查询结果不符: %+v", got)
+	}
+
+	user.Age = 21
+	if _, err := engine.ID(user.Id).Update(user); err != nil {
+		t.Fatalf("更新失败: %v", err)
+	}
+	if _, err := engine.ID(user.Id).Delete(new(User)); err != nil {
+		t.Fatalf("删除失败: %v", err)
+	}
+	if n, err := engine.Count(new(User)); err != nil || n != 0 {
+		t.Errorf("删除后记录数=%d err=%v", n, err)
+	}
+}
+
+func TestNewEngine_Defaults(t *testing.T) {
+	if _, err := NewEngine(Config{DSN: "x"}); err == nil {
+		t.Error("未指定驱动时应返回错误")
+	}
+	if _, err := NewEngine(Config{Driver: "no-such-driver", DSN: "x"}); err == nil {
+		t.Error("未注册的驱动应返回错误")
+	}
+
+	engine := newSQLiteEngine(t)
+	if got := engine.DB().Stats().MaxOpenConnections; got != 20 {
+		t.Errorf("默认最大连接数=%d want=20", got)
+	}
+}
+
+func TestTryReconnectWithConfig(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	breaker := NewCircuitBreaker(3)
+	cfg := Config{
+		Driver:  "sqlite",
+		DSN:     filepath.Join(t.TempDir(), "reconnect.db"),
+		Options: Options{MaxOpenConns: 3, LogLevel: "info", ShowSQL: true},
+		Logger:  zap.New(core),
+		Breaker: breaker,
+	}
+	var engine *xorm.Engine
+	if err := TryReconnectWithConfig(&engine, cfg, 1); err != nil {
+		t.Fatalf("重连失败: %v", err)
+	}
+	defer engine.Close()
+	if err := Ping(engine); err != nil {
+		t.Errorf("重连后 Ping 失败: %v", err)
+	}
+	if got := engine.DB().Stats().MaxOpenConnections; got != 3 {
+		t.Errorf("重连后应保留连接池配置: MaxOpenConns=%d", got)
+	}
+	if _, err := engine.Exec("SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+	if logs.Len() == 0 {
+		t.Error("重连后应保留 zap 日志")
+	}
+	breaker.Fail()
+	breaker.Fail()
+	breaker.Fail()
+	if _, err := engine.Exec("SELECT 1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("重连后应保留熔断器: %v", err)
+	}
+}
+
+func TestMonitorConnection_StopsOnClose(t *testing.T) {
+	engine := newSQLiteEngine(t)
+	done := make(chan struct{})
+	go func() {
+		monitorConnection(engine, NewCircuitBreaker(3), nil, 5*time.Millisecond)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("引擎未关闭时连接监控不应退出")
+	default:
+	}
+	engine.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("引擎关闭后连接监控应退出")
+	}
+}
+
+func TestNewEngine_MaskArgOptOut(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	engine, err := NewEngine(Config{
+		Driver:  "sqlite",
+		DSN:     filepath.Join(t.TempDir(), "mask.db"),
+		Options: Options{LogLevel: "info", ShowSQL: true},
+		Logger:  zap.New(core),
+		MaskArg: MaskArgKeepScalars,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	if _, err := engine.Exec("SELECT ?, ?", "13800138000", 20); err != nil {
+		t.Fatal(err)
+	}
+	entries := logs.FilterMessage("[SQL]").All()
+	if len(entries) == 0 {
+		t.Fatal("未输出 SQL 日志")
+	}
+	args, _ := entries[len(entries)-1].ContextMap()["args"].([]any)
+	if len(args) != 2 || args[0] != "1***0" || args[1] != 20 {
+		t.Errorf("Config.MaskArg 应作用于 SQL 日志: %v", args)
+	}
+}
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/godror/godror"
+)
+
+// 数据库错误分类，经 Classify 处理后可使用 errors.Is 判断
+var (
+	ErrNotFound       = errors.New("database: record not found")      // 无数据：sql.ErrNoRows、ORA-01403
+	ErrDuplicateKey   = errors.New("database: duplicate key")         // 唯一约束冲突：ORA-00001
+	ErrConstraint     = errors.New("database: constraint violation")  // 外键、检查、非空约束：ORA-02291/02292/02290/01400
+	ErrValueTooLarge  = errors.New("database: value too large")       // 值超出列长度或精度：ORA-12899/01438
+	ErrDeadlock       = errors.New("database: deadlock detected")     // 死锁：ORA-00060
+	ErrSerialization  = errors.New("database: serialization failure") // 无法串行访问：ORA-08177
+	ErrResourceBusy   = errors.New("database: resource busy")         // 资源被占用（NOWAIT）：ORA-00054
+	ErrTimeout        = errors.New("database: timeout")               // 超时：context.DeadlineExceeded、ORA-01013/12170
+	ErrConnectionLost = errors.New("database: connection lost")       // 连接断开或不可用：ORA-03113/03114/03135、driver.ErrBadConn 等
+)
+
+// Error 分类后的数据库错误，同时匹配分类错误与原始错误
+type Error struct {
+	Kind error // 分类，如 ErrDuplicateKey
+	Code int   // ORA 错误码，非 Oracle 错误为 0
+	Err  error // 原始错误
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap 支持 errors.Is(err, ErrDuplicateKey) 与 errors.As(err, &oraErr)
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// ORA 错误码与分类的对应关系
+var oraErrorKinds = map[int]error{
+	1:     ErrDuplicateKey,   // ORA-00001 违反唯一约束条件
+	1403:  ErrNotFound,       // ORA-01403 未找到任何数据
+	2290:  ErrConstraint,     // ORA-02290 违反检查约束条件
+	2291:  ErrConstraint,     // ORA-02291 违反完整约束条件 - 未找到父项关键字
+	2292:  ErrConstraint,     // ORA-02292 违反完整约束条件 - 已找到子记录
+	1400:  ErrConstraint,     // ORA-01400 无法将 NULL 插入
+	1407:  ErrConstraint,     // ORA-01407 无法更新为 NULL
+	12899: ErrValueTooLarge,  // ORA-12899 列的值太大
+	1438:  ErrValueTooLarge,  // ORA-01438 值大于此列指定的允许精度
+	60:    ErrDeadlock,       // ORA-00060 等待资源时检测到死锁
+	8177:  ErrSerialization,  // ORA-08177 无法串行访问此事务
+	54:    ErrResourceBusy,   // ORA-00054 资源正忙，但指定以 NOWAIT 方式获取资源
+	30006: ErrResourceBusy,   // ORA-30006 资源已被占用; 执行操作时出现 WAIT 超时
+	1013:  ErrTimeout,        // ORA-01013 用户请求取消当前的操作（调用超时）
+	12170: ErrTimeout,        // ORA-12170 出现连接超时
+	3113:  ErrConnectionLost, // ORA-03113 通信通道的文件结尾
+	3114:  ErrConnectionLost, // ORA-03114 未连接到 Oracle
+	3135:  ErrConnectionLost, // ORA-03135 连接失去联系
+	1012:  ErrConnectionLost, // ORA-01012 没有登录
+	1033:  ErrConnectionLost, // ORA-01033 Oracle 正在初始化或关闭
+	1034:  ErrConnectionLost, // ORA-01034 Oracle 不可用
+	1089:  ErrConnectionLost, // ORA-01089 正在执行立即关闭
+	1092:  ErrConnectionLost, // ORA-01092 Oracle 实例终止
+	12514: ErrConnectionLost, // ORA-12514 监听程序当前无法识别连接描述符中请求的服务
+	12528: ErrConnectionLost, // ORA-12528 所有适用的例程都不允许建立新连接
+	12537: ErrConnectionLost, // ORA-12537 连接已关闭
+	12541: ErrConnectionLost, // ORA-12541 无监听程序
+	12543: ErrConnectionLost, // ORA-12543 目标主机无法访问
+}
+
+// 非 Oracle 驱动的常见唯一约束冲突信息（SQLite / MySQL / PostgreSQL），便于测试与多数据库场景
+var duplicateKeyMessages = []string{"UNIQUE constraint failed", "Duplicate entry", "duplicate key value"}
+
+var oraCodePattern = regexp.MustCompile(`ORA-(\d{5})`)
+
+// OraCode 提取错误中的 ORA 错误码，不是 Oracle 错误时返回 0
+func OraCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if oerr, ok := godror.AsOraErr(err); ok && oerr.Code() > 0 {
+		return oerr.Code()
+	}
+	if m := oraCodePattern.FindStringSubmatch(err.Error()); m != nil {
+		code, _ := strconv.Atoi(m[1])
+		return code
+	}
+	return 0
+}
+
+// Classify 将驱动错误归类为 Err* 分类错误，无法归类时原样返回
+//
+//	if _, err := engine.Insert(&user); errors.Is(database.Classify(err), database.ErrDuplicateKey) {
+//	    // 用户已存在
+//	}
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	var classified *Error
+	if errors.As(err, &classified) {
+		return err
+	}
+	code := OraCode(err)
+	kind := oraErrorKinds[code]
+	if kind == nil {
+		kind = classifyGeneric(err)
+	}
+	if kind == nil {
+		return err
+	}
+	return &Error{Kind: kind, Code: code, Err: err}
+}
+
+// classifyGeneric 归类与具体数据库无关的错误
+func classifyGeneric(err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone):
+		return ErrConnectionLost
+	}
+	msg := err.Error()
+	for _, s := range duplicateKeyMessages {
+		if strings.Contains(msg, s) {
+			return ErrDuplicateKey
+		}
+	}
+	return nil
+}
+
+// kindOf 返回错误分类，无法归类时返回 nil
+func kindOf(err error) error {
+	var classified *Error
+	if errors.As(Classify(err), &classified) {
+		return classified.Kind
+	}
+	return nil
+}
+
+// IsTransient 是否为可重试的瞬时错误：死锁、序列化失败、连接断开；提交结果未知（ErrCommitUnknown）时不可重试
+func IsTransient(err error) bool {
+	if errors.Is(err, ErrCommitUnknown) {
+		return false
+	}
+	switch kindOf(err) {
+	case ErrDeadlock, ErrSerialization, ErrConnectionLost:
+		return true
+	}
+	return false
+}
+
+// IsDatabaseFailure 是否为数据库故障，用于熔断判断。仅连接断开、网络错误与超时计入：
+// ErrConnectionLost、ErrTimeout、ORA-125xx 监听与网络错误、net.Error。
+// SQL 错误（如 ORA-00942/00904）、数据错误（如 ORA-01722/01476）、约束冲突、死锁以及调用方取消均不计入，
+// 避免同一条错误语句反复执行导致整个数据库熔断
+func IsDatabaseFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	switch kindOf(err) {
+	case ErrConnectionLost, ErrTimeout:
+		return true
+	}
+	if code := OraCode(err); code >= 12500 && code <= 12599 {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
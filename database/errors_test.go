@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestClassify_OraCodes(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind error
+	}{
+		{errors.New("ORA-00001: unique constraint (APP.PK_USER) violated"), ErrDuplicateKey},
+		{fmt.Errorf("insert: %w", &oraTestErr{code: 2291}), ErrConstraint},
+		{errors.New("ORA-12899: value too large for column"), ErrValueTooLarge},
+		{&oraTestErr{code: 60}, ErrDeadlock},
+		{&oraTestErr{code: 54}, ErrResourceBusy},
+		{errors.New("dpiStmt_execute: ORA-03113: end-of-file on communication channel"), ErrConnectionLost},
+		{&oraTestErr{code: 1013}, ErrTimeout},
+		{fmt.Errorf("get: %w", sql.ErrNoRows), ErrNotFound},
+		{context.DeadlineExceeded, ErrTimeout},
+	}
+	for _, c := range cases {
+		got := Classify(c.err)
+		if !errors.Is(got, c.kind) {
+			t.Errorf("Classify(%v) 未归类为 %v", c.err, c.kind)
+		}
+		if !errors.Is(got, c.err) {
+			t.Errorf("Classify(%v) 丢失原始错误", c.err)
+		}
+	}
+
+	var oerr *oraTestErr
+	if !errors.As(Classify(&oraTestErr{code: 1}), &oerr) || oerr.code != 1 {
+		t.Error("分类后应仍可 errors.As 到驱动错误")
+	}
+	var dbErr *Error
+	if !errors.As(Classify(&oraTestErr{code: 1}), &dbErr) || dbErr.Code != 1 {
+		t.Errorf("分类错误应携带 ORA 错误码: %+v", dbErr)
+	}
+
+	unknown := errors.New("ORA-00942: table or view does not exist")
+	if Classify(unknown) != unknown {
+		t.Error("无法归类的错误应原样返回")
+	}
+	if Classify(nil) != nil {
+		t.Error("nil 应返回 nil")
+	}
+}
+
+func TestClassify_SQLiteDuplicateKey(t *testing.T) {
+	engine := newSQLiteEngine(t)
+	if err := AutoMigrate(engine); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Insert(&User{Id: 1, Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := engine.Insert(&User{Id: 1, Name: "b"})
+	if !errors.Is(Classify(err), ErrDuplicateKey) {
+		t.Errorf("主键冲突应归类为 ErrDuplicateKey: %v", err)
+	}
+}
+
+func TestIsDatabaseFailure(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{sql.ErrNoRows, false},
+		{&oraTestErr{code: 1}, false},
+		{&oraTestErr{code: 60}, false},
+		{context.Canceled, false},
+		{ErrCircuitOpen, false},
+		{&oraTestErr{code: 3113}, true},
+		{context.DeadlineExceeded, true},
+		{driver.ErrBadConn, true},
+		{&oraTestErr{code: 12505}, true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{errors.New("ORA-00942: table or view does not exist"), false},
+		{errors.New("ORA-00904: invalid identifier"), false},
+		{&oraTestErr{code: 1722}, false},
+		{&oraTestErr{code: 1476}, false},
+		{errors.New("ORA-04031: unable to allocate shared memory"), false},
+		{errors.New("no such table: users"), false},
+	}
+	for _, c := range cases {
+		if got := IsDatabaseFailure(c.err); got != c.want {
+			t.Errorf("IsDatabaseFailure(%v)=%v want=%v", c.err, got, c.want)
+		}
+	}
+
+	b := NewCircuitBreakerWithConfig(BreakerConfig{FailureThreshold: 1})
+	b.Execute(func() error { return &oraTestErr{code: 1} })
+	if b.State() != StateClosed {
+		t.Error("唯一约束冲突不应触发熔断")
+	}
+	// 同一条错误 SQL 反复执行不应熔断整个数据库
+	for range 10 {
+		b.Execute(func() error { return errors.New("ORA-00942: table or view does not exist") })
+		b.Execute(func() error { return &oraTestErr{code: 1722} })
+	}
+	if b.State() != StateClosed {
+		t.Error("SQL 错误与数据错误不应触发熔断")
+	}
+	b.Execute(func() error { return &oraTestErr{code: 3114} })
+	if !b.IsOpen() {
+		t.Error("连接断开应触发熔断")
+	}
+}
@@ -0,0 +1,374 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"xorm.io/xorm"
+)
+
+// 从库负载均衡策略
+const (
+	PolicyRoundRobin   = "round_robin"   // 轮询（默认）
+	PolicyRandom       = "random"        // 随机
+	PolicyWeightRandom = "weight_random" // 按权重随机，权重见 GroupConfig.Weights
+	PolicyLeastConn    = "least_conn"    // 最少使用中连接
+)
+
+// GroupConfig 读写分离配置
+type GroupConfig struct {
+	Driver              string        `mapstructure:"driver"`                // 驱动名
+	Primary             string        `mapstructure:"primary"`               // 主库 DSN
+	Replicas            []string      `mapstructure:"replicas"`              // 从库 DSN 列表
+	Policy              string        `mapstructure:"policy"`                // 负载均衡策略，默认 round_robin
+	Weights             []int         `mapstructure:"weights"`               // weight_random 策略下各从库权重，缺省为 1
+	HealthCheckInterval time.Duration `mapstructure:"health_check_interval"` // 从库健康检查间隔，默认 10 秒
+	Options             `mapstructure:",squash"`
+
+	Logger  *zap.Logger       `mapstructure:"-"` // 日志，为 nil 时输出到标准输出
+	Breaker *CircuitBreaker   `mapstructure:"-"` // 主库熔断器，为 nil 时不启用
+	Tracer  SpanExporter      `mapstructure:"-"` // 主从库共用的 SQL 链路追踪，为 nil 时不启用
+	MaskArg func(arg any) any `mapstructure:"-"` // 主从库共用的参数脱敏，默认 MaskArg
+}
+
+// Group 读写分离引擎组：写操作、事务与 FOR UPDATE 查询走主库，自动提交的 SELECT 按策略分发到健康的从库，
+// 从库健康检查失败时自动摘除，恢复后重新加入，全部从库不可用时回退到主库。
+type Group struct {
+	*xorm.EngineGroup
+
+	primary  *xorm.Engine
+	replicas []*xorm.Engine
+	healthy  []atomic.Bool
+	policy   string
+	weights  []int
+	logger   *zap.Logger
+
+	next atomic.Uint64
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewEngineGroup 由主库与从库 DSN 创建读写分离引擎组
+// 主库连接失败时返回错误；从库连接失败时仅记录日志并标记为不健康，由健康检查恢复
+func NewEngineGroup(cfg GroupConfig) (*Group, error) {
+	switch cfg.Policy {
+	case "":
+		cfg.Policy = PolicyRoundRobin
+	case PolicyRoundRobin, PolicyRandom, PolicyWeightRandom, PolicyLeastConn:
+	default:
+		return nil, fmt.Errorf("database: unknown group policy %q", cfg.Policy)
+	}
+	if cfg.HealthCheckInterval <= 0 {
+		cfg.HealthCheckInterval = 10 * time.Second
+	}
+
+	primary, err := NewEngine(Config{Driver: cfg.Driver, DSN: cfg.Primary, Options: cfg.Options, Logger: cfg.Logger, Breaker: cfg.Breaker, Tracer: cfg.Tracer, MaskArg: cfg.MaskArg})
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Group{
+		primary:  primary,
+		replicas: make([]*xorm.Engine, 0, len(cfg.Replicas)),
+		healthy:  make([]atomic.Bool, len(cfg.Replicas)),
+		policy:   cfg.Policy,
+		weights:  make([]int, len(cfg.Replicas)),
+		logger:   cfg.Logger,
+		stop:     make(chan struct{}),
+	}
+	for i, dsn := range cfg.Replicas {
+		replicaCfg := Config{Driver: cfg.Driver, DSN: dsn, Options: cfg.Options, Logger: cfg.Logger, Tracer: cfg.Tracer, MaskArg: cfg.MaskArg}
+		replica, _, err := openEngine(replicaCfg)
+		if err != nil {
+			g.closeEngines()
+			return nil, err
+		}
+		setupEngine(replica, replicaCfg)
+		g.replicas = append(g.replicas, replica)
+		g.healthy[i].Store(replica.Ping() == nil)
+		if !g.healthy[i].Load() && g.logger != nil {
+			g.logger.Warn("[XORM] 从库连接失败，暂不参与读请求", zap.Int("replica", i))
+		}
+		g.weights[i] = 1
+		if i < len(cfg.Weights) && cfg.Weights[i] > 0 {
+			g.weights[i] = cfg.Weights[i]
+		}
+	}
+
+	g.EngineGroup, err = xorm.NewEngineGroup(primary, g.replicas, g)
+	if err != nil {
+		g.closeEngines()
+		return nil, err
+	}
+
+	if len(g.replicas) > 0 {
+		go g.monitorReplicas(cfg.HealthCheckInterval)
+	}
+	return g, nil
+}
+
+// Primary 返回主库引擎，用于需要强一致读取的查询，例如 g.Primary().ID(id).Get(&user)
+func (g *Group) Primary() *xorm.Engine {
+	return g.primary
+}
+
+// NewSession 返回组会话；没有健康从库时返回主库会话。
+// xorm 只有一个从库时不经过策略直接使用该从库，因此回退在创建会话时处理，而不是把主库加入从库列表
+func (g *Group) NewSession() *xorm.Session {
+	if g.HealthyReplicas() == 0 {
+		return g.primary.NewSession()
+	}
+	return g.EngineGroup.NewSession()
+}
+
+// Context 返回使用 ctx 的组会话，没有健康从库时返回主库会话
+func (g *Group) Context(ctx context.Context) *xorm.Session {
+	if g.HealthyReplicas() == 0 {
+		return g.primary.Context(ctx)
+	}
+	return g.EngineGroup.Context(ctx)
+}
+
+// Query 在组会话中执行查询，没有健康从库时走主库
+func (g *Group) Query(sqlOrArgs ...interface{}) ([]map[string][]byte, error) {
+	if g.HealthyReplicas() == 0 {
+		return g.primary.Query(sqlOrArgs...)
+	}
+	return g.EngineGroup.Query(sqlOrArgs...)
+}
+
+// QueryString 在组会话中执行查询，没有健康从库时走主库
+func (g *Group) QueryString(sqlOrArgs ...interface{}) ([]map[string]string, error) {
+	if g.HealthyReplicas() == 0 {
+		return g.primary.QueryString(sqlOrArgs...)
+	}
+	return g.EngineGroup.QueryString(sqlOrArgs...)
+}
+
+// QueryInterface 在组会话中执行查询，没有健康从库时走主库
+func (g *Group) QueryInterface(sqlOrArgs ...interface{}) ([]map[string]interface{}, error) {
+	if g.HealthyReplicas() == 0 {
+		return g.primary.QueryInterface(sqlOrArgs...)
+	}
+	return g.EngineGroup.QueryInterface(sqlOrArgs...)
+}
+
+// Rows 在组会话中查询，没有健康从库时走主库
+func (g *Group) Rows(bean interface{}) (*xorm.Rows, error) {
+	if g.HealthyReplicas() == 0 {
+		return g.primary.Rows(bean)
+	}
+	return g.EngineGroup.Rows(bean)
+}
+
+// 以下覆盖 xorm.Engine 的常用查询入口：嵌入的 Engine 方法会创建主库会话，
+// 覆盖后改为经 g.NewSession() 创建组会话，使 g.Where(...).Find、g.Get、g.SQL、g.Count 等查询按策略走从库。
+// 返回 *xorm.Session 的方法与 xorm 一致，非事务查询无需调用 Close。
+
+// Where 以条件创建组会话
+func (g *Group) Where(query interface{}, args ...interface{}) *xorm.Session {
+	return g.NewSession().Where(query, args...)
+}
+
+// ID 以主键条件创建组会话
+func (g *Group) ID(id interface{}) *xorm.Session {
+	return g.NewSession().ID(id)
+}
+
+// SQL 以原始 SQL 创建组会话
+func (g *Group) SQL(query interface{}, args ...interface{}) *xorm.Session {
+	return g.NewSession().SQL(query, args...)
+}
+
+// Table 以指定表创建组会话
+func (g *Group) Table(tableNameOrBean interface{}) *xorm.Session {
+	return g.NewSession().Table(tableNameOrBean)
+}
+
+// Cols 以指定列创建组会话
+func (g *Group) Cols(columns ...string) *xorm.Session {
+	return g.NewSession().Cols(columns...)
+}
+
+// Select 以指定 SELECT 子句创建组会话
+func (g *Group) Select(str string) *xorm.Session {
+	return g.NewSession().Select(str)
+}
+
+// In 以 IN 条件创建组会话
+func (g *Group) In(column string, args ...interface{}) *xorm.Session {
+	return g.NewSession().In(column, args...)
+}
+
+// Join 以连接查询创建组会话
+func (g *Group) Join(joinOperator string, tablename interface{}, condition interface{}, args ...interface{}) *xorm.Session {
+	return g.NewSession().Join(joinOperator, tablename, condition, args...)
+}
+
+// OrderBy 以排序条件创建组会话
+func (g *Group) OrderBy(order interface{}, args ...interface{}) *xorm.Session {
+	return g.NewSession().OrderBy(order, args...)
+}
+
+// Limit 以分页条件创建组会话
+func (g *Group) Limit(limit int, start ...int) *xorm.Session {
+	return g.NewSession().Limit(limit, start...)
+}
+
+// Get 在组会话中查询单条记录
+func (g *Group) Get(beans ...interface{}) (bool, error) {
+	session := g.NewSession()
+	defer session.Close()
+	return session.Get(beans...)
+}
+
+// Exist 在组会话中判断记录是否存在
+func (g *Group) Exist(bean ...interface{}) (bool, error) {
+	session := g.NewSession()
+	defer session.Close()
+	return session.Exist(bean...)
+}
+
+// Find 在组会话中查询多条记录
+func (g *Group) Find(beans interface{}, condiBeans ...interface{}) error {
+	session := g.NewSession()
+	defer session.Close()
+	return session.Find(beans, condiBeans...)
+}
+
+// FindAndCount 在组会话中查询记录与总数
+func (g *Group) FindAndCount(rowsSlicePtr interface{}, condiBean ...interface{}) (int64, error) {
+	session := g.NewSession()
+	defer session.Close()
+	return session.FindAndCount(rowsSlicePtr, condiBean...)
+}
+
+// Iterate 在组会话中逐条遍历记录
+func (g *Group) Iterate(bean interface{}, fun xorm.IterFunc) error {
+	session := g.NewSession()
+	defer session.Close()
+	return session.Iterate(bean, fun)
+}
+
+// Count 在组会话中统计记录数
+func (g *Group) Count(bean ...interface{}) (int64, error) {
+	session := g.NewSession()
+	defer session.Close()
+	return session.Count(bean...)
+}
+
+// Sum 在组会话中求和
+func (g *Group) Sum(bean interface{}, colName string) (float64, error) {
+	session := g.NewSession()
+	defer session.Close()
+	return session.Sum(bean, colName)
+}
+
+// SumInt 在组会话中按整数求和
+func (g *Group) SumInt(bean interface{}, colName string) (int64, error) {
+	session := g.NewSession()
+	defer session.Close()
+	return session.SumInt(bean, colName)
+}
+
+// Replicas 返回全部从库引擎（含已摘除的）
+func (g *Group) Replicas() []*xorm.Engine {
+	return g.replicas
+}
+
+// HealthyReplicas 当前健康的从库数量
+func (g *Group) HealthyReplicas() int {
+	n := 0
+	for i := range g.healthy {
+		if g.healthy[i].Load() {
+			n++
+		}
+	}
+	return n
+}
+
+// Slave 实现 xorm.GroupPolicy，按策略从健康从库中选择，没有健康从库时返回主库
+func (g *Group) Slave(*xorm.EngineGroup) *xorm.Engine {
+	candidates := make([]int, 0, len(g.replicas))
+	for i := range g.replicas {
+		if g.healthy[i].Load() {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return g.primary
+	}
+
+	switch g.policy {
+	case PolicyRandom:
+		return g.replicas[candidates[rand.IntN(len(candidates))]]
+	case PolicyWeightRandom:
+		total := 0
+		for _, i := range candidates {
+			total += g.weights[i]
+		}
+		n := rand.IntN(total)
+		for _, i := range candidates {
+			if n < g.weights[i] {
+				return g.replicas[i]
+			}
+			n -= g.weights[i]
+		}
+	case PolicyLeastConn:
+		best := candidates[0]
+		for _, i := range candidates[1:] {
+			if g.replicas[i].DB().Stats().InUse < g.replicas[best].DB().Stats().InUse {
+				best = i
+			}
+		}
+		return g.replicas[best]
+	}
+	return g.replicas[candidates[g.next.Add(1)%uint64(len(candidates))]]
+}
+
+// monitorReplicas 定时检查从库，失败摘除、恢复后重新加入
+func (g *Group) monitorReplicas(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+		}
+		for i, replica := range g.replicas {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			err := replica.PingContext(ctx)
+			cancel()
+			was := g.healthy[i].Swap(err == nil)
+			if g.logger == nil || was == (err == nil) {
+				continue
+			}
+			if err != nil {
+				g.logger.Error("[XORM] 从库健康检查失败，已摘除", zap.Int("replica", i), zap.Error(err))
+			} else {
+				g.logger.Info("[XORM] 从库恢复，重新加入", zap.Int("replica", i))
+			}
+		}
+	}
+}
+
+// Close 停止健康检查并关闭主库与从库
+func (g *Group) Close() error {
+	g.once.Do(func() { close(g.stop) })
+	return g.closeEngines()
+}
+
+func (g *Group) closeEngines() error {
+	errs := []error{g.primary.Close()}
+	for _, replica := range g.replicas {
+		errs = append(errs, replica.Close())
+	}
+	return errors.Join(errs...)
+}
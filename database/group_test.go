@@ -0,0 +1,194 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"xorm.io/xorm"
+)
+
+// seedName 在独立的 SQLite 文件中写入一行标识数据，用于区分查询落到哪个库
+func seedName(t *testing.T, dsn, name string) {
+	t.Helper()
+	engine, err := xorm.NewEngine("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	if _, err := engine.Exec("CREATE TABLE node (name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Exec("INSERT INTO node (name) VALUES (?)", name); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func queryNode(t *testing.T, session *xorm.Session) string {
+	t.Helper()
+	defer session.Close()
+	rows, err := session.QueryString("SELECT name FROM node")
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("查询失败: rows=%v err=%v", rows, err)
+	}
+	return rows[0]["name"]
+}
+
+func TestEngineGroup_ReadWriteSplitAndFailover(t *testing.T) {
+	dir := t.TempDir()
+	primaryDSN := filepath.Join(dir, "primary.db")
+	replicaDSN := filepath.Join(dir, "replica.db")
+	seedName(t, primaryDSN, "primary")
+	seedName(t, replicaDSN, "replica")
+
+	g, err := NewEngineGroup(GroupConfig{
+		Driver:              "sqlite",
+		Primary:             primaryDSN,
+		Replicas:            []string{replicaDSN},
+		HealthCheckInterval: 20 * time.Millisecond,
+		Logger:              zap.NewNop(),
+	})
+	if err != nil {
+		t.Fatalf("创建引擎组失败: %v", err)
+	}
+	defer g.Close()
+
+	if got := queryNode(t, g.NewSession()); got != "replica" {
+		t.Errorf("SELECT 应走从库, got=%s", got)
+	}
+	if got := queryNode(t, g.Primary().NewSession()); got != "primary" {
+		t.Errorf("Primary() 应走主库, got=%s", got)
+	}
+
+	// 关闭从库连接模拟故障，健康检查后应摘除并回退到主库
+	g.Replicas()[0].Close()
+	deadline := time.Now().Add(time.Second)
+	for g.HealthyReplicas() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if g.HealthyReplicas() != 0 {
+		t.Fatal("故障从库未被摘除")
+	}
+	if got := queryNode(t, g.NewSession()); got != "primary" {
+		t.Errorf("无健康从库时应回退主库, got=%s", got)
+	}
+	if rows, err := g.QueryString("SELECT name FROM node"); err != nil || len(rows) != 1 || rows[0]["name"] != "primary" {
+		t.Errorf("无健康从库时 QueryString 应回退主库: rows=%v err=%v", rows, err)
+	}
+}
+
+type node struct {
+	Name string `xorm:"'name'"`
+}
+
+func (node) TableName() string { return "node" }
+
+func TestEngineGroup_EngineReadsUseReplica(t *testing.T) {
+	dir := t.TempDir()
+	primaryDSN := filepath.Join(dir, "primary.db")
+	replicaDSN := filepath.Join(dir, "replica.db")
+	seedName(t, primaryDSN, "primary")
+	seedName(t, replicaDSN, "replica")
+
+	g, err := NewEngineGroup(GroupConfig{
+		Driver:              "sqlite",
+		Primary:             primaryDSN,
+		Replicas:            []string{replicaDSN},
+		HealthCheckInterval: time.Hour,
+		Logger:              zap.NewNop(),
+	})
+	if err != nil {
+		t.Fatalf("创建引擎组失败: %v", err)
+	}
+	defer g.Close()
+
+	var nodes []node
+	if err := g.Where("name <> ?", "").Find(&nodes); err != nil || len(nodes) != 1 || nodes[0].Name != "replica" {
+		t.Errorf("g.Where().Find 应走从库: nodes=%v err=%v", nodes, err)
+	}
+	var n node
+	if has, err := g.Get(&n); err != nil || !has || n.Name != "replica" {
+		t.Errorf("g.Get 应走从库: node=%v err=%v", n, err)
+	}
+	if has, err := g.SQL("SELECT name FROM node").Get(&n); err != nil || !has || n.Name != "replica" {
+		t.Errorf("g.SQL 应走从库: node=%v err=%v", n, err)
+	}
+	if count, err := g.Where("name = ?", "replica").Count(new(node)); err != nil || count != 1 {
+		t.Errorf("g.Where().Count 应走从库: count=%d err=%v", count, err)
+	}
+	if count, err := g.Count(&node{Name: "replica"}); err != nil || count != 1 {
+		t.Errorf("g.Count 应走从库: count=%d err=%v", count, err)
+	}
+
+	// 写操作仍走主库
+	if _, err := g.Table("node").Insert(&node{Name: "written"}); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := g.Primary().Count(&node{Name: "written"}); err != nil || count != 1 {
+		t.Errorf("写操作应走主库: count=%d err=%v", count, err)
+	}
+}
+
+func TestEngineGroup_PrimaryNotInSlaves(t *testing.T) {
+	dir := t.TempDir()
+	g, err := NewEngineGroup(GroupConfig{
+		Driver:              "sqlite",
+		Primary:             filepath.Join(dir, "p.db"),
+		Replicas:            []string{filepath.Join(dir, "r.db")},
+		HealthCheckInterval: time.Hour,
+		Logger:              zap.NewNop(),
+	})
+	if err != nil {
+		t.Fatalf("创建引擎组失败: %v", err)
+	}
+	defer g.Close()
+
+	// 主库不应出现在从库列表中，否则 SetMaxOpenConns / Ping / Close 会重复作用于主库
+	slaves := g.Slaves()
+	if len(slaves) != 1 || slaves[0] == g.Primary() {
+		t.Errorf("从库列表应只包含从库: %v", slaves)
+	}
+	g.SetMaxOpenConns(7)
+	if n := g.Primary().DB().Stats().MaxOpenConnections; n != 7 {
+		t.Errorf("主库最大连接数应为 7, got=%d", n)
+	}
+}
+
+func TestEngineGroup_Policies(t *testing.T) {
+	dir := t.TempDir()
+	cfg := GroupConfig{
+		Driver:              "sqlite",
+		Primary:             filepath.Join(dir, "p.db"),
+		Replicas:            []string{filepath.Join(dir, "r1.db"), filepath.Join(dir, "r2.db")},
+		Policy:              PolicyWeightRandom,
+		Weights:             []int{1, 0},
+		HealthCheckInterval: time.Hour,
+		Logger:              zap.NewNop(),
+	}
+	g, err := NewEngineGroup(cfg)
+	if err != nil {
+		t.Fatalf("创建引擎组失败: %v", err)
+	}
+	defer g.Close()
+
+	seen := map[*xorm.Engine]int{}
+	for range 100 {
+		seen[g.Slave(nil)]++
+	}
+	if seen[g.Primary()] != 0 || len(seen) != 2 {
+		t.Errorf("权重缺省为 1 时两个从库都应被选中且不选主库: %v", seen)
+	}
+
+	g.healthy[0].Store(false)
+	for range 10 {
+		if g.Slave(nil) != g.Replicas()[1] {
+			t.Fatal("已摘除的从库不应被选中")
+		}
+	}
+
+	cfg.Policy = "unknown"
+	if _, err := NewEngineGroup(cfg); err == nil {
+		t.Error("未知策略应返回错误")
+	}
+}
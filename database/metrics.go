@@ -0,0 +1,264 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/muchinfo/mtp2-common-lib/logger"
+	"go.uber.org/zap"
+	"xorm.io/xorm"
+	"xorm.io/xorm/contexts"
+)
+
+// DefaultLatencyBuckets 默认耗时直方图上界，最后一个桶之外的计入 +Inf
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 500 * time.Millisecond, time.Second, 5 * time.Second,
+}
+
+// otherFingerprint 超过 MaxStatements 后新语句统一计入该项
+const otherFingerprint = "<other>"
+
+// MetricsConfig SQL 统计配置
+type MetricsConfig struct {
+	SlowThreshold time.Duration     // 慢 SQL 阈值，0 表示不输出慢 SQL 日志
+	Buckets       []time.Duration   // 耗时直方图上界（升序），默认 DefaultLatencyBuckets
+	MaxStatements int               // 最多统计的语句指纹数，默认 1000
+	MaskArg       func(arg any) any // 慢 SQL 日志中参数脱敏，默认 MaskArg（全部脱敏）
+	Logger        *zap.Logger       // 慢 SQL 日志，为 nil 时不输出
+}
+
+// StatementStats 单个语句指纹的统计快照
+type StatementStats struct {
+	Fingerprint string          // 归一化后的 SQL
+	Count       uint64          // 执行次数
+	Errors      uint64          // 失败次数
+	Slow        uint64          // 慢 SQL 次数
+	Total       time.Duration   // 总耗时
+	Max         time.Duration   // 最大耗时
+	Buckets     []time.Duration // 直方图上界，与 Histogram 前 len(Buckets) 项对应
+	Histogram   []uint64        // 各桶计数（非累计），最后一项为 +Inf
+}
+
+// Avg 平均耗时
+func (s StatementStats) Avg() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// SQLMetrics 按 SQL 指纹统计执行次数、耗时直方图与错误数，并输出带参数（脱敏）与调用位置的慢 SQL 日志。
+// 作为 xorm hook 安装：database.UseMetrics(engine, metrics)，或通过 Config.Metrics 由 NewEngine 安装
+type SQLMetrics struct {
+	config MetricsConfig
+
+	mutex sync.Mutex
+	stats map[string]*StatementStats
+}
+
+// NewSQLMetrics 创建 SQL 统计
+func NewSQLMetrics(config MetricsConfig) *SQLMetrics {
+	if len(config.Buckets) == 0 {
+		config.Buckets = DefaultLatencyBuckets
+	}
+	if config.MaxStatements <= 0 {
+		config.MaxStatements = 1000
+	}
+	if config.MaskArg == nil {
+		config.MaskArg = MaskArg
+	}
+	return &SQLMetrics{config: config, stats: make(map[string]*StatementStats)}
+}
+
+// UseMetrics 为引擎安装 SQL 统计 hook
+func UseMetrics(engine *xorm.Engine, metrics *SQLMetrics) {
+	engine.AddHook(metrics)
+}
+
+// BeforeProcess 实现 contexts.Hook，耗时由 xorm 在 ContextHook.ExecuteTime 中给出
+func (m *SQLMetrics) BeforeProcess(c *contexts.ContextHook) (context.Context, error) {
+	return c.Ctx, nil
+}
+
+// AfterProcess 实现 contexts.Hook
+func (m *SQLMetrics) AfterProcess(c *contexts.ContextHook) error {
+	m.Observe(c.SQL, c.ExecuteTime, c.Err)
+	if m.config.SlowThreshold > 0 && c.ExecuteTime > m.config.SlowThreshold && m.config.Logger != nil {
+		m.logSlow(c)
+	}
+	return nil
+}
+
+// Observe 记录一次执行
+func (m *SQLMetrics) Observe(sql string, cost time.Duration, err error) {
+	fingerprint := Fingerprint(sql)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	s, ok := m.stats[fingerprint]
+	if !ok {
+		if len(m.stats) >= m.config.MaxStatements {
+			fingerprint = otherFingerprint
+			s = m.stats[fingerprint]
+		}
+		if s == nil {
+			s = &StatementStats{
+				Fingerprint: fingerprint,
+				Buckets:     m.config.Buckets,
+				Histogram:   make([]uint64, len(m.config.Buckets)+1),
+			}
+			m.stats[fingerprint] = s
+		}
+	}
+	s.Count++
+	s.Total += cost
+	s.Max = max(s.Max, cost)
+	if err != nil {
+		s.Errors++
+	}
+	if m.config.SlowThreshold > 0 && cost > m.config.SlowThreshold {
+		s.Slow++
+	}
+	i := sort.Search(len(m.config.Buckets), func(i int) bool { return cost <= m.config.Buckets[i] })
+	s.Histogram[i]++
+}
+
+// Snapshot 返回全部语句统计的副本，按总耗时降序
+func (m *SQLMetrics) Snapshot() []StatementStats {
+	m.mutex.Lock()
+	result := make([]StatementStats, 0, len(m.stats))
+	for _, s := range m.stats {
+		c := *s
+		c.Histogram = append([]uint64(nil), s.Histogram...)
+		result = append(result, c)
+	}
+	m.mutex.Unlock()
+	sort.Slice(result, func(i, j int) bool { return result[i].Total > result[j].Total })
+	return result
+}
+
+// TopSlowest 返回平均耗时最长的 n 个语句
+func (m *SQLMetrics) TopSlowest(n int) []StatementStats {
+	result := m.Snapshot()
+	sort.Slice(result, func(i, j int) bool { return result[i].Avg() > result[j].Avg() })
+	if n >= 0 && n < len(result) {
+		result = result[:n]
+	}
+	return result
+}
+
+// Reset 清空统计
+func (m *SQLMetrics) Reset() {
+	m.mutex.Lock()
+	m.stats = make(map[string]*StatementStats)
+	m.mutex.Unlock()
+}
+
+// logSlow 输出慢 SQL：语句、脱敏参数、耗时、影响行数、错误与业务调用位置
+func (m *SQLMetrics) logSlow(c *contexts.ContextHook) {
+	fields := append(logger.ContextFields(c.Ctx),
+		zap.String("sql", c.SQL),
+		zap.Any("args", maskArgs(c.Args, m.config.MaskArg)),
+		zap.Duration("cost", c.ExecuteTime),
+		zap.String("caller", sqlCaller()),
+	)
+	if c.Result != nil {
+		if rows, err := c.Result.RowsAffected(); err == nil {
+			fields = append(fields, zap.Int64("rows", rows))
+		}
+	}
+	if c.Err != nil {
+		fields = append(fields, zap.Error(c.Err))
+	}
+	m.config.Logger.Warn("[XORM] 慢SQL", fields...)
+}
+
+var (
+	stringLiteralPattern = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numberPattern        = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	placeholderPattern   = regexp.MustCompile(`(?::\w+|\$\d+|\?)`)
+	inListPattern        = regexp.MustCompile(`(?i)\bIN\s*\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	spacePattern         = regexp.MustCompile(`\s+`)
+)
+
+// Fingerprint 归一化 SQL：字面量与绑定变量替换为 ?，IN 列表合并，空白压缩，使同类语句聚合统计
+func Fingerprint(sql string) string {
+	s := stringLiteralPattern.ReplaceAllString(sql, "?")
+	s = placeholderPattern.ReplaceAllString(s, "?")
+	s = numberPattern.ReplaceAllString(s, "?")
+	s = inListPattern.ReplaceAllString(s, "IN (...)")
+	return strings.TrimSpace(spacePattern.ReplaceAllString(s, " "))
+}
+
+// MaskArg 默认参数脱敏，所有类型均脱敏：字符串仅保留首尾字符，二进制只输出长度，
+// 数值（如金额、证件号）、布尔、时间等其他类型输出 ***，nil 原样输出。
+// 需要在日志中保留数值等参数时显式使用 MaskArgKeepScalars
+func MaskArg(arg any) any {
+	switch v := arg.(type) {
+	case nil:
+		return nil
+	case string:
+		n := utf8.RuneCountInString(v)
+		if n <= 2 {
+			return "***"
+		}
+		first, _ := utf8.DecodeRuneInString(v)
+		last, _ := utf8.DecodeLastRuneInString(v)
+		return string(first) + "***" + string(last)
+	case []byte:
+		return fmt.Sprintf("<%d bytes>", len(v))
+	}
+	return "***"
+}
+
+// MaskArgKeepScalars 数值、布尔、时间原样输出，其余同 MaskArg；
+// 用于确认数值参数不含敏感信息、需要在日志中排查时显式替换默认脱敏，例如 Config.MaskArg = MaskArgKeepScalars
+func MaskArgKeepScalars(arg any) any {
+	switch v := arg.(type) {
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, time.Time:
+		return v
+	}
+	return MaskArg(arg)
+}
+
+// maskArgs 按 mask 逐个脱敏参数，返回新切片
+func maskArgs(args []any, mask func(arg any) any) []any {
+	masked := make([]any, len(args))
+	for i, arg := range args {
+		masked[i] = mask(arg)
+	}
+	return masked
+}
+
+// 本包源码目录，用于在调用栈中跳过本包（测试文件除外）
+var packageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// sqlCaller 调用栈中第一个不属于 xorm、database/sql 与本包的函数，格式 函数名 文件:行号
+func sqlCaller() string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		internal := strings.HasPrefix(frame.Function, "xorm.io/") ||
+			strings.HasPrefix(frame.Function, "database/sql.") ||
+			(filepath.Dir(frame.File) == packageDir && !strings.HasSuffix(frame.File, "_test.go"))
+		if !internal {
+			return fmt.Sprintf("%s %s:%d", frame.Function, filepath.Base(frame.File), frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
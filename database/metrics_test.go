@@ -0,0 +1,148 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFingerprint(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM t WHERE id = 10 AND name = 'it''s'":      "SELECT * FROM t WHERE id = ? AND name = ?",
+		"SELECT  *\n FROM t WHERE id = :1 AND code IN (:2, :3)": "SELECT * FROM t WHERE id = ? AND code IN (...)",
+		"SELECT * FROM t1 WHERE id IN (?,?,?) AND price > 1.5":  "SELECT * FROM t1 WHERE id IN (...) AND price > ?",
+		"UPDATE t SET a = $1 WHERE b = $2":                      "UPDATE t SET a = ? WHERE b = ?",
+	}
+	for sql, want := range cases {
+		if got := Fingerprint(sql); got != want {
+			t.Errorf("Fingerprint(%q)=%q want=%q", sql, got, want)
+		}
+	}
+}
+
+func TestMaskArg(t *testing.T) {
+	if got := MaskArg("13800138000"); got != "1***0" {
+		t.Errorf("字符串脱敏=%v", got)
+	}
+	if got := MaskArg("张三丰"); got != "张***丰" {
+		t.Errorf("多字节字符串脱敏=%v", got)
+	}
+	if got := MaskArg("ab"); got != "***" {
+		t.Errorf("短字符串应全部脱敏: %v", got)
+	}
+	for _, arg := range []any{int64(6222021234567890), 3.14, true, time.Now()} {
+		if got := MaskArg(arg); got != "***" {
+			t.Errorf("%T 默认应脱敏: %v", arg, got)
+		}
+	}
+	if got := MaskArg(nil); got != nil {
+		t.Errorf("nil 应原样输出: %v", got)
+	}
+	if got := MaskArg([]byte("secret")); got != "<6 bytes>" {
+		t.Errorf("二进制应只输出长度: %v", got)
+	}
+
+	if got := MaskArgKeepScalars(int64(42)); got != int64(42) {
+		t.Errorf("MaskArgKeepScalars 数值应原样输出: %v", got)
+	}
+	if got := MaskArgKeepScalars("13800138000"); got != "1***0" {
+		t.Errorf("MaskArgKeepScalars 字符串仍应脱敏: %v", got)
+	}
+}
+
+func TestSQLMetrics_ObserveAndTopSlowest(t *testing.T) {
+	m := NewSQLMetrics(MetricsConfig{Buckets: []time.Duration{time.Millisecond, 10 * time.Millisecond}, MaxStatements: 2})
+	m.Observe("SELECT * FROM a WHERE id = 1", 500*time.Microsecond, nil)
+	m.Observe("SELECT * FROM a WHERE id = 2", 5*time.Millisecond, errors.New("x"))
+	m.Observe("SELECT * FROM b", 50*time.Millisecond, nil)
+	m.Observe("SELECT * FROM c", time.Second, nil)
+
+	stats := m.Snapshot()
+	if len(stats) != 3 {
+		t.Fatalf("超过 MaxStatements 的语句应计入 %s: %+v", otherFingerprint, stats)
+	}
+	top := m.TopSlowest(1)
+	if len(top) != 1 || top[0].Fingerprint != otherFingerprint {
+		t.Errorf("TopSlowest=%+v", top)
+	}
+	for _, s := range stats {
+		if s.Fingerprint != "SELECT * FROM a WHERE id = ?" {
+			continue
+		}
+		if s.Count != 2 || s.Errors != 1 || s.Max != 5*time.Millisecond {
+			t.Errorf("统计不符: %+v", s)
+		}
+		if s.Histogram[0] != 1 || s.Histogram[1] != 1 || s.Histogram[2] != 0 {
+			t.Errorf("直方图不符: %v", s.Histogram)
+		}
+	}
+
+	m.Reset()
+	if len(m.Snapshot()) != 0 {
+		t.Error("Reset 后应清空")
+	}
+}
+
+func TestSQLMetrics_SlowLogWithCaller(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	metrics := NewSQLMetrics(MetricsConfig{SlowThreshold: time.Nanosecond, Logger: zap.New(core)})
+	breaker := NewCircuitBreakerWithConfig(BreakerConfig{
+		FailureThreshold: 1,
+		Cooldown:         time.Hour,
+		IsFailure:        func(err error) bool { return err != nil }, // SQLite 无法模拟连接故障，任意错误计入
+	})
+	engine, err := NewEngine(Config{
+		Driver:  "sqlite",
+		DSN:     filepath.Join(t.TempDir(), "metrics.db"),
+		Logger:  zap.NewNop(),
+		Metrics: metrics,
+		Breaker: breaker,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	if err := AutoMigrate(engine); err != nil {
+		t.Fatal(err)
+	}
+	logs.TakeAll()
+
+	if _, err := engine.Insert(&User{Name: "李四光", Age: 30}); err != nil {
+		t.Fatal(err)
+	}
+	entries := logs.FilterMessage("[XORM] 慢SQL").All()
+	if len(entries) == 0 {
+		t.Fatal("未输出慢 SQL 日志")
+	}
+	fields := entries[0].ContextMap()
+	if caller, _ := fields["caller"].(string); !strings.Contains(caller, "TestSQLMetrics_SlowLogWithCaller") {
+		t.Errorf("caller 应为业务调用位置: %q", caller)
+	}
+	if args, _ := fields["args"].([]any); len(args) != 2 || args[0] != "李***光" {
+		t.Errorf("参数应脱敏: %v", fields["args"])
+	}
+	if fields["rows"] != int64(1) {
+		t.Errorf("应记录影响行数: %v", fields["rows"])
+	}
+
+	found := false
+	for _, s := range metrics.Snapshot() {
+		if strings.HasPrefix(s.Fingerprint, "INSERT INTO") && s.Count == 1 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("未统计 INSERT: %+v", metrics.Snapshot())
+	}
+
+	// 与熔断 hook 同时安装时，熔断仍按 SQL 结果计数
+	engine.Exec("SELECT * FROM no_such_table")
+	if !breaker.IsOpen() {
+		t.Error("多个 hook 同时安装时熔断器应正常记录失败")
+	}
+}
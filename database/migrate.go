@@ -0,0 +1,499 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/muchinfo/mtp2-common-lib/ulidgen"
+	"go.uber.org/zap"
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+// ErrMigrationLocked 等待迁移锁超时，另一个实例正在执行迁移
+var ErrMigrationLocked = errors.New("database: migration locked by another process")
+
+// Migration 一个版本的迁移，SQL 与 Go 函数二选一，Go 函数优先
+type Migration struct {
+	Version int64
+	Name    string
+	UpSQL   string
+	DownSQL string
+	Up      func(session *xorm.Session) error
+	Down    func(session *xorm.Session) error
+}
+
+func (m *Migration) hasDown() bool {
+	return m.Down != nil || m.DownSQL != ""
+}
+
+// MigrationStatus 迁移状态
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// MigratorConfig 迁移配置
+type MigratorConfig struct {
+	Table       string        // 迁移历史表，默认 schema_migrations
+	LockTable   string        // 迁移锁表，默认 schema_migrations_lock
+	LockWait    time.Duration // 等待锁的最长时间，默认 1 分钟
+	LockTimeout time.Duration // 锁超过该时间未刷新视为进程已退出，可被抢占，默认 10 分钟；持有期间每 1/3 该时长刷新一次
+}
+
+// migrationRecord 迁移历史记录
+type migrationRecord struct {
+	Version   int64     `xorm:"pk 'version'"`
+	Name      string    `xorm:"varchar(255) 'name'"`
+	AppliedAt time.Time `xorm:"'applied_at'"`
+}
+
+// migrationLock 迁移锁，表中仅有 id=1 一行时表示已加锁
+type migrationLock struct {
+	Id       int64     `xorm:"pk 'id'"`
+	Owner    string    `xorm:"varchar(64) 'owner'"`
+	LockedAt time.Time `xorm:"'locked_at'"`
+}
+
+// Migrator 版本化迁移执行器：按版本号顺序执行 SQL 或 Go 迁移，在历史表中记录已执行版本，
+// 通过锁表防止多个实例同时迁移。
+// 注意：Oracle 的 DDL 会隐式提交，包含 DDL 的迁移失败时无法整体回滚，应保持每个迁移尽量小。
+type Migrator struct {
+	engine     *xorm.Engine
+	config     MigratorConfig
+	logger     *zap.Logger
+	migrations map[int64]*Migration
+}
+
+// NewMigrator 创建迁移执行器
+// logger 允许为 nil，若为 nil 则不输出日志
+func NewMigrator(engine *xorm.Engine, config MigratorConfig, logger *zap.Logger) *Migrator {
+	if config.Table == "" {
+		config.Table = "schema_migrations"
+	}
+	if config.LockTable == "" {
+		config.LockTable = "schema_migrations_lock"
+	}
+	if config.LockWait <= 0 {
+		config.LockWait = time.Minute
+	}
+	if config.LockTimeout <= 0 {
+		config.LockTimeout = 10 * time.Minute
+	}
+	return &Migrator{engine: engine, config: config, logger: logger, migrations: make(map[int64]*Migration)}
+}
+
+// Add 注册迁移，版本号重复时返回错误
+func (m *Migrator) Add(migrations ...Migration) error {
+	for i := range migrations {
+		mig := migrations[i]
+		if mig.Version <= 0 {
+			return fmt.Errorf("database: invalid migration version %d", mig.Version)
+		}
+		if mig.Up == nil && mig.UpSQL == "" {
+			return fmt.Errorf("database: migration %d has no up", mig.Version)
+		}
+		if _, ok := m.migrations[mig.Version]; ok {
+			return fmt.Errorf("database: duplicate migration version %d", mig.Version)
+		}
+		m.migrations[mig.Version] = &mig
+	}
+	return nil
+}
+
+// 迁移文件名：<版本>_<名称>.<up|down>[.<方言>].sql，例如 0001_create_user.up.sql、0002_seq.up.oracle.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+?)\.(up|down)(?:\.([a-z0-9]+))?\.sql$`)
+
+// AddFS 从文件系统（通常为 embed.FS）的 dir 目录加载 SQL 迁移文件
+// 同一版本存在当前数据库方言（oracle / sqlite / mysql / postgres / mssql）的文件时优先使用，其他方言的文件忽略
+//
+//	//go:embed migrations/*.sql
+//	var migrationFS embed.FS
+//	migrator.AddFS(migrationFS, "migrations")
+func (m *Migrator) AddFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	dialect := dialectName(m.engine)
+	loaded := make(map[int64]*Migration)
+	specific := make(map[string]bool) // 已加载方言专用文件的 "版本/up|down"
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		fileDialect := match[4]
+		if fileDialect != "" && fileDialect != dialect {
+			continue
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		key := match[1] + "/" + match[3]
+		if fileDialect == "" && specific[key] {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		mig := loaded[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: match[2]}
+			loaded[version] = mig
+		}
+		if match[3] == "up" {
+			mig.UpSQL = string(data)
+		} else {
+			mig.DownSQL = string(data)
+		}
+		if fileDialect != "" {
+			specific[key] = true
+		}
+	}
+	for _, mig := range loaded {
+		if err := m.Add(*mig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dialectName 迁移文件使用的方言名
+func dialectName(engine *xorm.Engine) string {
+	dbType := engine.Dialect().URI().DBType
+	if dbType == schemas.SQLITE {
+		return "sqlite"
+	}
+	return string(dbType)
+}
+
+// Up 执行全部未执行的迁移
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.UpTo(ctx, 0)
+}
+
+// UpTo 执行版本号不大于 version 的未执行迁移，version 为 0 表示全部
+func (m *Migrator) UpTo(ctx context.Context, version int64) error {
+	return m.withLock(ctx, func(ctx context.Context, applied map[int64]migrationRecord) error {
+		for _, mig := range m.sorted() {
+			if version > 0 && mig.Version > version {
+				break
+			}
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := m.run(ctx, mig, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down 按版本号从大到小回滚最近 steps 个已执行的迁移
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(ctx context.Context, applied map[int64]migrationRecord) error {
+		versions := make([]int64, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+		for i := 0; i < steps && i < len(versions); i++ {
+			mig, ok := m.migrations[versions[i]]
+			if !ok {
+				return fmt.Errorf("database: migration %d applied but not registered", versions[i])
+			}
+			if !mig.hasDown() {
+				return fmt.Errorf("database: migration %d has no down", mig.Version)
+			}
+			if err := m.run(ctx, mig, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Status 返回全部已注册迁移的执行状态，按版本号排序
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	if err := m.sync(); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.sorted() {
+		rec, ok := applied[mig.Version]
+		statuses = append(statuses, MigrationStatus{Version: mig.Version, Name: mig.Name, Applied: ok, AppliedAt: rec.AppliedAt})
+	}
+	return statuses, nil
+}
+
+// Version 返回已执行的最大版本号，未执行任何迁移时返回 0
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	if err := m.sync(); err != nil {
+		return 0, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var version int64
+	for v := range applied {
+		version = max(version, v)
+	}
+	return version, nil
+}
+
+func (m *Migrator) sorted() []*Migration {
+	migs := make([]*Migration, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		migs = append(migs, mig)
+	}
+	sort.Slice(migs, func(i, j int) bool { return migs[i].Version < migs[j].Version })
+	return migs
+}
+
+func (m *Migrator) sync() error {
+	if err := m.engine.Table(m.config.Table).Sync(new(migrationRecord)); err != nil {
+		return err
+	}
+	return m.engine.Table(m.config.LockTable).Sync(new(migrationLock))
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]migrationRecord, error) {
+	var records []migrationRecord
+	if err := m.engine.Context(ctx).Table(m.config.Table).Find(&records); err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]migrationRecord, len(records))
+	for _, rec := range records {
+		applied[rec.Version] = rec
+	}
+	return applied, nil
+}
+
+// withLock 加锁后读取已执行版本并执行 fn，结束后释放锁。持有期间定时刷新锁，锁被抢占时取消传给 fn 的 ctx
+func (m *Migrator) withLock(ctx context.Context, fn func(ctx context.Context, applied map[int64]migrationRecord) error) error {
+	if err := m.sync(); err != nil {
+		return err
+	}
+	owner, err := lockOwner()
+	if err != nil {
+		return err
+	}
+	if err := m.lock(ctx, owner); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	go m.heartbeat(ctx, owner, cancel, done)
+	defer func() {
+		// 先停止刷新再删除锁，避免刷新落空被误判为锁丢失
+		cancel(nil)
+		<-done
+		if _, err := m.engine.Table(m.config.LockTable).Where("id = ? AND owner = ?", 1, owner).Delete(new(migrationLock)); err != nil && m.logger != nil {
+			m.logger.Error("[Migrate] 释放迁移锁失败", zap.Error(err))
+		}
+	}()
+
+	applied, err := m.applied(ctx)
+	if err == nil {
+		err = fn(ctx, applied)
+	}
+	if cause := context.Cause(ctx); err != nil && errors.Is(cause, ErrMigrationLocked) {
+		err = errors.Join(cause, err)
+	}
+	return err
+}
+
+// heartbeat 持有锁期间每 LockTimeout/3 刷新 locked_at，避免耗时较长的迁移被其他实例判定超时而抢占；
+// 锁记录已不属于 owner 时以 ErrMigrationLocked 取消迁移
+func (m *Migrator) heartbeat(ctx context.Context, owner string, cancel context.CancelCauseFunc, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(m.config.LockTimeout / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		n, err := m.engine.Table(m.config.LockTable).Where("id = ? AND owner = ?", 1, owner).
+			Cols("locked_at").Update(&migrationLock{LockedAt: time.Now()})
+		if err != nil {
+			if m.logger != nil {
+				m.logger.Warn("[Migrate] 刷新迁移锁失败", zap.Error(err))
+			}
+			continue
+		}
+		if n == 0 {
+			if m.logger != nil {
+				m.logger.Error("[Migrate] 迁移锁已被抢占，停止迁移", zap.String("owner", owner))
+			}
+			cancel(fmt.Errorf("%w: lock lost by %s", ErrMigrationLocked, owner))
+			return
+		}
+	}
+}
+
+// lock 插入锁记录，已被占用时等待；锁超过 LockTimeout 未释放时视为持有者已退出并抢占
+func (m *Migrator) lock(ctx context.Context, owner string) error {
+	deadline := time.Now().Add(m.config.LockWait)
+	for {
+		_, err := m.engine.Context(ctx).Table(m.config.LockTable).Insert(&migrationLock{Id: 1, Owner: owner, LockedAt: time.Now()})
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(Classify(err), ErrDuplicateKey) {
+			return err
+		}
+
+		var held migrationLock
+		has, gerr := m.engine.Context(ctx).Table(m.config.LockTable).ID(1).Get(&held)
+		if gerr != nil {
+			return gerr
+		}
+		if has && time.Since(held.LockedAt) > m.config.LockTimeout {
+			if m.logger != nil {
+				m.logger.Warn("[Migrate] 迁移锁已超时，抢占", zap.String("owner", held.Owner), zap.Time("locked_at", held.LockedAt))
+			}
+			if _, err := m.engine.Context(ctx).Table(m.config.LockTable).Where("id = ? AND owner = ?", 1, held.Owner).Delete(new(migrationLock)); err != nil {
+				return fmt.Errorf("migrate: remove stale lock held by %s: %w", held.Owner, err)
+			}
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: held by %s", ErrMigrationLocked, held.Owner)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// lockOwner 锁持有者标识：主机名 + 进程号 + ULID
+func lockOwner() (string, error) {
+	host, _ := os.Hostname()
+	id, err := ulidgen.GenerateULID()
+	if err != nil {
+		return "", err
+	}
+	return truncateString(fmt.Sprintf("%s:%d:%s", host, os.Getpid(), id), 64), nil
+}
+
+// truncateString 超长时保留末尾 n 字节（ULID 部分）
+func truncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
+
+// run 在事务中执行一个迁移并更新历史表
+func (m *Migrator) run(ctx context.Context, mig *Migration, up bool) error {
+	direction := "up"
+	if !up {
+		direction = "down"
+	}
+	start := time.Now()
+	err := WithTxOptions(ctx, m.engine, TxOptions{MaxRetries: -1}, func(s *xorm.Session) error {
+		fn, sqlText := mig.Up, mig.UpSQL
+		if !up {
+			fn, sqlText = mig.Down, mig.DownSQL
+		}
+		if fn != nil {
+			if err := fn(s); err != nil {
+				return err
+			}
+		} else {
+			stmts, err := SplitSQL(sqlText)
+			if err != nil {
+				return err
+			}
+			for _, stmt := range stmts {
+				if _, err := s.Exec(stmt); err != nil {
+					return fmt.Errorf("%w\n%s", err, stmt)
+				}
+			}
+		}
+		if up {
+			_, err := s.Table(m.config.Table).Insert(&migrationRecord{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now()})
+			return err
+		}
+		_, err := s.Table(m.config.Table).Where("version = ?", mig.Version).Delete(new(migrationRecord))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("database: migration %d_%s %s: %w", mig.Version, mig.Name, direction, err)
+	}
+	if m.logger != nil {
+		m.logger.Info("[Migrate] 迁移完成", zap.Int64("version", mig.Version), zap.String("name", mig.Name),
+			zap.String("direction", direction), zap.Duration("cost", time.Since(start)))
+	}
+	return nil
+}
+
+// PL/SQL 块的起始，需以单独一行 / 结束
+var plsqlPattern = regexp.MustCompile(`(?i)^\s*(DECLARE|BEGIN|CREATE\s+(OR\s+REPLACE\s+)?(PROCEDURE|FUNCTION|TRIGGER|PACKAGE|TYPE))\b`)
+
+// SplitSQL 将迁移脚本拆分为单条语句（Oracle 驱动不支持一次执行多条语句）：
+// 普通语句以行尾 ; 结束（去掉 ;），PL/SQL 块（DECLARE / BEGIN / CREATE PROCEDURE 等）以单独一行 / 结束（保留块内 ;），
+// 整行 -- 注释忽略；单行超过 1MB 时返回错误
+func SplitSQL(script string) ([]string, error) {
+	var (
+		stmts []string
+		buf   strings.Builder
+	)
+	flush := func() {
+		if s := strings.TrimSpace(buf.String()); s != "" {
+			stmts = append(stmts, s)
+		}
+		buf.Reset()
+	}
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "/" {
+			flush()
+			continue
+		}
+		if buf.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+		if plsqlPattern.MatchString(buf.String()) {
+			continue
+		}
+		if strings.HasSuffix(trimmed, ";") {
+			s := strings.TrimSpace(buf.String())
+			buf.Reset()
+			buf.WriteString(strings.TrimSuffix(s, ";"))
+			flush()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("database: split sql: %w", err)
+	}
+	flush()
+	return stmts, nil
+}
@@ -0,0 +1,263 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"xorm.io/xorm"
+)
+
+var testMigrationFS = fstest.MapFS{
+	"migrations/0001_create_account.up.sql": {Data: []byte(`
+-- 账户表
+CREATE TABLE account (
+    id      INTEGER PRIMARY KEY,
+    name    VARCHAR(100) NOT NULL,
+    balance NUMERIC(18,2) DEFAULT 0
+);
+CREATE INDEX idx_account_name ON account (name);
+`)},
+	"migrations/0001_create_account.down.sql": {Data: []byte("DROP TABLE account;")},
+	"migrations/0002_seed.up.sql":             {Data: []byte("INSERT INTO account (id, name) VALUES (1, 'generic');")},
+	"migrations/0002_seed.up.sqlite.sql":      {Data: []byte("INSERT INTO account (id, name) VALUES (1, 'sqlite');")},
+	"migrations/0002_seed.up.oracle.sql":      {Data: []byte("INSERT INTO account (id, name) VALUES (1, 'oracle');")},
+	"migrations/0002_seed.down.sql":           {Data: []byte("DELETE FROM account WHERE id = 1;")},
+	"migrations/README.md":                    {Data: []byte("忽略非迁移文件")},
+}
+
+func newTestMigrator(t *testing.T, engine *xorm.Engine, config MigratorConfig) *Migrator {
+	t.Helper()
+	m := NewMigrator(engine, config, nil)
+	if err := m.AddFS(testMigrationFS, "migrations"); err != nil {
+		t.Fatalf("加载迁移文件失败: %v", err)
+	}
+	err := m.Add(Migration{
+		Version: 3,
+		Name:    "add_email",
+		Up: func(s *xorm.Session) error {
+			_, err := s.Exec("ALTER TABLE account ADD COLUMN email VARCHAR(255)")
+			return err
+		},
+		Down: func(s *xorm.Session) error {
+			_, err := s.Exec("ALTER TABLE account DROP COLUMN email")
+			return err
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMigrator_UpDown(t *testing.T) {
+	engine := newSQLiteEngine(t)
+	ctx := context.Background()
+	m := newTestMigrator(t, engine, MigratorConfig{})
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up 失败: %v", err)
+	}
+	if v, _ := m.Version(ctx); v != 3 {
+		t.Fatalf("版本=%d want=3", v)
+	}
+	rows, err := engine.QueryString("SELECT name, email FROM account WHERE id = 1")
+	if err != nil || len(rows) != 1 || rows[0]["name"] != "sqlite" {
+		t.Fatalf("应执行 sqlite 方言的迁移: rows=%v err=%v", rows, err)
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("重复 Up 应为空操作: %v", err)
+	}
+
+	if err := m.Down(ctx, 2); err != nil {
+		t.Fatalf("Down 失败: %v", err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil || len(statuses) != 3 {
+		t.Fatalf("Status 失败: %v %v", statuses, err)
+	}
+	if !statuses[0].Applied || statuses[1].Applied || statuses[2].Applied {
+		t.Errorf("回滚后状态不符: %+v", statuses)
+	}
+
+	if err := m.UpTo(ctx, 2); err != nil {
+		t.Fatalf("UpTo 失败: %v", err)
+	}
+	if v, _ := m.Version(ctx); v != 2 {
+		t.Errorf("UpTo 后版本=%d want=2", v)
+	}
+}
+
+func TestMigrator_FailedMigrationNotRecorded(t *testing.T) {
+	engine := newSQLiteEngine(t)
+	ctx := context.Background()
+	m := NewMigrator(engine, MigratorConfig{}, nil)
+	m.Add(
+		Migration{Version: 1, Name: "ok", UpSQL: "CREATE TABLE t1 (id INTEGER);"},
+		Migration{Version: 2, Name: "bad", UpSQL: "INSERT INTO t1 (id) VALUES (1);\nINSERT INTO no_such_table VALUES (1);"},
+	)
+	if err := m.Up(ctx); err == nil {
+		t.Fatal("失败的迁移应返回错误")
+	}
+	if v, _ := m.Version(ctx); v != 1 {
+		t.Errorf("失败的迁移不应记录, 版本=%d", v)
+	}
+	if n, _ := engine.Table("t1").Count(); n != 0 {
+		t.Errorf("失败迁移的事务应回滚, count=%d", n)
+	}
+	if err := m.Add(Migration{Version: 1, Name: "dup", UpSQL: "SELECT 1"}); err == nil {
+		t.Error("重复版本应返回错误")
+	}
+}
+
+func TestMigrator_Lock(t *testing.T) {
+	engine := newSQLiteEngine(t)
+	ctx := context.Background()
+	m := newTestMigrator(t, engine, MigratorConfig{LockWait: 10 * time.Millisecond, LockTimeout: time.Hour})
+	if err := m.sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := engine.Table("schema_migrations_lock").Insert(&migrationLock{Id: 1, Owner: "other", LockedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(ctx); !errors.Is(err, ErrMigrationLocked) {
+		t.Fatalf("锁被占用时应返回 ErrMigrationLocked: %v", err)
+	}
+
+	// 过期的锁可被抢占
+	engine.Table("schema_migrations_lock").ID(1).Cols("locked_at").Update(&migrationLock{LockedAt: time.Now().Add(-2 * time.Hour)})
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("应抢占过期的锁: %v", err)
+	}
+	if n, _ := engine.Table("schema_migrations_lock").Count(); n != 0 {
+		t.Error("迁移结束后应释放锁")
+	}
+}
+
+func TestSplitSQL(t *testing.T) {
+	script := `
+-- 注释
+CREATE TABLE t (id NUMBER);
+INSERT INTO t VALUES (1);
+
+CREATE OR REPLACE TRIGGER trg_t
+BEFORE INSERT ON t
+FOR EACH ROW
+BEGIN
+  :new.id := 1;
+END;
+/
+BEGIN
+  NULL;
+END;
+/
+UPDATE t SET id = 2
+WHERE id = 1;
+`
+	got, err := SplitSQL(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"CREATE TABLE t (id NUMBER)",
+		"INSERT INTO t VALUES (1)",
+		"CREATE OR REPLACE TRIGGER trg_t\nBEFORE INSERT ON t\nFOR EACH ROW\nBEGIN\n  :new.id := 1;\nEND;",
+		"BEGIN\n  NULL;\nEND;",
+		"UPDATE t SET id = 2\nWHERE id = 1",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("拆分结果=%q", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("语句[%d]=%q want=%q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSplitSQL_LongLine(t *testing.T) {
+	script := "INSERT INTO t VALUES ('" + strings.Repeat("x", 2*1024*1024) + "');"
+	if stmts, err := SplitSQL(script); err == nil {
+		t.Errorf("超长行应返回错误而不是截断: %d 条语句", len(stmts))
+	}
+}
+
+func TestMigrator_LockHeartbeat(t *testing.T) {
+	engine := newSQLiteEngine(t)
+	m := NewMigrator(engine, MigratorConfig{LockTimeout: 30 * time.Millisecond}, nil)
+	if err := m.sync(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Table("schema_migrations_lock").Insert(&migrationLock{Id: 1, Owner: "self", LockedAt: time.Now().Add(-time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	done := make(chan struct{})
+	go m.heartbeat(ctx, "self", cancel, done)
+
+	// 持有期间刷新 locked_at，其他实例不会判定超时而抢占
+	deadline := time.Now().Add(time.Second)
+	for {
+		// 与刷新并发时 SQLite 可能返回 SQLITE_BUSY，重试即可
+		var held migrationLock
+		if has, err := engine.Table("schema_migrations_lock").ID(1).Get(&held); err == nil && has && time.Since(held.LockedAt) < time.Minute {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("持有期间应刷新 locked_at")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 锁被他人抢占后取消迁移
+	if err := stealLock(engine); err != nil {
+		t.Fatalf("删除锁记录失败: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("锁丢失后应停止刷新")
+	}
+	if !errors.Is(context.Cause(ctx), ErrMigrationLocked) {
+		t.Errorf("锁丢失时应以 ErrMigrationLocked 取消: %v", context.Cause(ctx))
+	}
+}
+
+// stealLock 模拟他人抢占：删除锁记录，与刷新并发时 SQLite 可能返回 SQLITE_BUSY，1 秒内重试
+func stealLock(engine *xorm.Engine) error {
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err := engine.Table("schema_migrations_lock").Where("id = ?", 1).Delete(new(migrationLock))
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMigrator_LockLost(t *testing.T) {
+	engine := newSQLiteEngine(t)
+	ctx := context.Background()
+	m := NewMigrator(engine, MigratorConfig{LockTimeout: 30 * time.Millisecond}, nil)
+	m.Add(
+		Migration{Version: 1, Name: "steal", Up: func(s *xorm.Session) error {
+			if err := stealLock(engine); err != nil {
+				return err
+			}
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		}},
+		Migration{Version: 2, Name: "after", UpSQL: "CREATE TABLE after_steal (id INTEGER);"},
+	)
+	if err := m.Up(ctx); !errors.Is(err, ErrMigrationLocked) {
+		t.Errorf("锁丢失时应返回 ErrMigrationLocked: %v", err)
+	}
+	if v, _ := m.Version(ctx); v >= 2 {
+		t.Errorf("锁丢失后不应继续执行迁移, 版本=%d", v)
+	}
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/log"
+)
+
+// Options 连接池与日志选项，可通过 viper（mapstructure 标签）从配置文件加载，例如：
+//
+//	database:
+//	  driver: oracle
+//	  dsn: user/pwd@host:port/sid
+//	  max_open_conns: 50
+//	  max_idle_conns: 10
+//	  conn_max_lifetime: 30m
+//	  show_sql: false
+//	  log_level: warn
+type Options struct {
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`     // 最大连接数，默认 20
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`     // 最大空闲连接数，默认 5
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"` // 连接最大空闲时间，0 表示不限制
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`  // 连接最大存活时间，默认 30 分钟
+	ShowSQL         bool          `mapstructure:"show_sql"`           // 是否输出 SQL，默认不输出
+	LogLevel        string        `mapstructure:"log_level"`          // 日志级别：debug / info / warn / error / off，默认 info
+	SlowThreshold   time.Duration `mapstructure:"slow_threshold"`     // 慢 SQL 阈值，0 表示不统计
+	PingRetries     int           `mapstructure:"ping_retries"`       // 启动时 Ping 失败的重试次数，默认 3，负数表示不重试
+	PingInterval    time.Duration `mapstructure:"ping_interval"`      // Ping 重试间隔，默认 2 秒
+}
+
+// DefaultOptions 默认选项
+func DefaultOptions() Options {
+	return Options{
+		MaxOpenConns:    20,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: 30 * time.Minute,
+		LogLevel:        "info",
+		PingRetries:     3,
+		PingInterval:    2 * time.Second,
+	}
+}
+
+// withDefaults 未设置的字段使用默认值
+func (o Options) withDefaults() Options {
+	d := DefaultOptions()
+	if o.MaxOpenConns == 0 {
+		o.MaxOpenConns = d.MaxOpenConns
+	}
+	if o.MaxIdleConns == 0 {
+		o.MaxIdleConns = d.MaxIdleConns
+	}
+	if o.ConnMaxLifetime == 0 {
+		o.ConnMaxLifetime = d.ConnMaxLifetime
+	}
+	if o.LogLevel == "" {
+		o.LogLevel = d.LogLevel
+	}
+	if o.PingRetries == 0 {
+		o.PingRetries = d.PingRetries
+	}
+	if o.PingInterval <= 0 {
+		o.PingInterval = d.PingInterval
+	}
+	return o
+}
+
+// ParseLogLevel 解析日志级别字符串
+func ParseLogLevel(level string) (log.LogLevel, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return log.LOG_DEBUG, nil
+	case "", "info":
+		return log.LOG_INFO, nil
+	case "warn", "warning":
+		return log.LOG_WARNING, nil
+	case "error", "err":
+		return log.LOG_ERR, nil
+	case "off", "none":
+		return log.LOG_OFF, nil
+	}
+	return log.LOG_UNKNOWN, fmt.Errorf("database: unknown log level %q", level)
+}
+
+// SetPoolLimits 运行时调整连接池上限，参数为 0 时保持不变
+func SetPoolLimits(engine *xorm.Engine, maxOpenConns, maxIdleConns int) {
+	if maxOpenConns > 0 {
+		engine.SetMaxOpenConns(maxOpenConns)
+	}
+	if maxIdleConns > 0 {
+		engine.SetMaxIdleConns(maxIdleConns)
+	}
+}
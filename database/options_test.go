@@ -0,0 +1,111 @@
+package database
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"xorm.io/xorm/log"
+)
+
+const testDatabaseYAML = `
+database:
+  driver: sqlite
+  dsn: test.db
+  max_open_conns: 50
+  max_idle_conns: 10
+  conn_max_idle_time: 5m
+  conn_max_lifetime: 1h
+  show_sql: true
+  log_level: warn
+  slow_threshold: 200ms
+  ping_retries: -1
+`
+
+func TestOptions_LoadFromViper(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(testDatabaseYAML)); err != nil {
+		t.Fatalf("读取配置失败: %v", err)
+	}
+	var cfg Config
+	if err := v.UnmarshalKey("database", &cfg); err != nil {
+		t.Fatalf("解析配置失败: %v", err)
+	}
+
+	want := Options{
+		MaxOpenConns:    50,
+		MaxIdleConns:    10,
+		ConnMaxIdleTime: 5 * time.Minute,
+		ConnMaxLifetime: time.Hour,
+		ShowSQL:         true,
+		LogLevel:        "warn",
+		SlowThreshold:   200 * time.Millisecond,
+		PingRetries:     -1,
+	}
+	if cfg.Driver != "sqlite" || cfg.DSN != "test.db" {
+		t.Errorf("driver/dsn 解析错误: %+v", cfg)
+	}
+	if cfg.Options != want {
+		t.Errorf("选项解析错误: got=%+v want=%+v", cfg.Options, want)
+	}
+	if got := cfg.Options.withDefaults().PingInterval; got != 2*time.Second {
+		t.Errorf("未配置的 PingInterval 应使用默认值: %v", got)
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	cases := map[string]log.LogLevel{
+		"":      log.LOG_INFO,
+		"debug": log.LOG_DEBUG,
+		"WARN":  log.LOG_WARNING,
+		"error": log.LOG_ERR,
+		"off":   log.LOG_OFF,
+	}
+	for s, want := range cases {
+		if got, err := ParseLogLevel(s); err != nil || got != want {
+			t.Errorf("ParseLogLevel(%q)=%v,%v want=%v", s, got, err, want)
+		}
+	}
+	if _, err := ParseLogLevel("verbose"); err == nil {
+		t.Error("未知级别应返回错误")
+	}
+}
+
+func TestNewEngine_OptionsAndSetPoolLimits(t *testing.T) {
+	engine, err := NewEngine(Config{
+		Driver: "sqlite",
+		DSN:    filepath.Join(t.TempDir(), "options.db"),
+		Options: Options{
+			MaxOpenConns: 8,
+			LogLevel:     "error",
+		},
+		Logger: zap.NewNop(),
+	})
+	if err != nil {
+		t.Fatalf("创建引擎失败: %v", err)
+	}
+	defer engine.Close()
+
+	if got := engine.DB().Stats().MaxOpenConnections; got != 8 {
+		t.Errorf("最大连接数=%d want=8", got)
+	}
+	if engine.Logger().IsShowSQL() {
+		t.Error("默认不应输出 SQL")
+	}
+	if got := engine.Logger().Level(); got != log.LOG_ERR {
+		t.Errorf("日志级别=%v want=%v", got, log.LOG_ERR)
+	}
+
+	SetPoolLimits(engine, 30, 0)
+	if got := engine.DB().Stats().MaxOpenConnections; got != 30 {
+		t.Errorf("调整后最大连接数=%d want=30", got)
+	}
+
+	if _, err := NewEngine(Config{Driver: "sqlite", DSN: "x", Options: Options{LogLevel: "verbose"}}); err == nil {
+		t.Error("非法日志级别应返回错误")
+	}
+}
@@ -0,0 +1,503 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"xorm.io/builder"
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+// DefaultPageSize 分页大小未指定时的默认值
+const DefaultPageSize = 20
+
+// ErrInvalidCursor 游标无法解析，或与当前排序条件不匹配
+var ErrInvalidCursor = errors.New("database: invalid cursor")
+
+// Filter 查询条件与排序构造器，方法可链式调用，nil Filter 表示无条件。
+// 列名在执行时按引擎方言加引号，与 xorm 建表、查询的列名保持一致
+type Filter struct {
+	conds  []condFunc
+	orders []orderColumn
+}
+
+// condFunc 按引擎的引号规则生成条件
+type condFunc func(quote func(string) string) builder.Cond
+
+type orderColumn struct {
+	column string
+	desc   bool
+}
+
+// NewFilter 创建空条件
+func NewFilter() *Filter {
+	return &Filter{}
+}
+
+// Eq column = value
+func (f *Filter) Eq(column string, value any) *Filter {
+	return f.add(func(quote func(string) string) builder.Cond { return builder.Eq{quote(column): value} })
+}
+
+// Ne column <> value
+func (f *Filter) Ne(column string, value any) *Filter {
+	return f.add(func(quote func(string) string) builder.Cond { return builder.Neq{quote(column): value} })
+}
+
+// Gt column > value
+func (f *Filter) Gt(column string, value any) *Filter {
+	return f.add(func(quote func(string) string) builder.Cond { return builder.Gt{quote(column): value} })
+}
+
+// Gte column >= value
+func (f *Filter) Gte(column string, value any) *Filter {
+	return f.add(func(quote func(string) string) builder.Cond { return builder.Gte{quote(column): value} })
+}
+
+// Lt column < value
+func (f *Filter) Lt(column string, value any) *Filter {
+	return f.add(func(quote func(string) string) builder.Cond { return builder.Lt{quote(column): value} })
+}
+
+// Lte column <= value
+func (f *Filter) Lte(column string, value any) *Filter {
+	return f.add(func(quote func(string) string) builder.Cond { return builder.Lte{quote(column): value} })
+}
+
+// Like column LIKE '%value%'，value 中已含 % 时按原样匹配
+func (f *Filter) Like(column, value string) *Filter {
+	return f.add(func(quote func(string) string) builder.Cond { return builder.Like{quote(column), value} })
+}
+
+// In column IN (values...)，values 为空时条件恒假
+func (f *Filter) In(column string, values ...any) *Filter {
+	return f.add(func(quote func(string) string) builder.Cond { return builder.In(quote(column), values...) })
+}
+
+// NotIn column NOT IN (values...)
+func (f *Filter) NotIn(column string, values ...any) *Filter {
+	return f.add(func(quote func(string) string) builder.Cond { return builder.NotIn(quote(column), values...) })
+}
+
+// Between column BETWEEN from AND to
+func (f *Filter) Between(column string, from, to any) *Filter {
+	return f.add(func(quote func(string) string) builder.Cond {
+		return builder.Between{Col: quote(column), LessVal: from, MoreVal: to}
+	})
+}
+
+// IsNull column IS NULL
+func (f *Filter) IsNull(column string) *Filter {
+	return f.add(func(quote func(string) string) builder.Cond { return builder.IsNull{quote(column)} })
+}
+
+// NotNull column IS NOT NULL
+func (f *Filter) NotNull(column string) *Filter {
+	return f.add(func(quote func(string) string) builder.Cond { return builder.NotNull{quote(column)} })
+}
+
+// Expr 原生条件片段，如 Expr("amount > frozen + ?", 100)，列名不做处理
+func (f *Filter) Expr(sql string, args ...any) *Filter {
+	return f.Cond(builder.Expr(sql, args...))
+}
+
+// Or 将多个 Filter 的条件以 OR 组合后并入当前条件（忽略其排序）
+func (f *Filter) Or(filters ...*Filter) *Filter {
+	return f.add(func(quote func(string) string) builder.Cond {
+		conds := make([]builder.Cond, 0, len(filters))
+		for _, other := range filters {
+			if cond := other.cond(quote); cond != nil {
+				conds = append(conds, cond)
+			}
+		}
+		return builder.Or(conds...)
+	})
+}
+
+// Cond 并入任意 xorm builder 条件，列名不做处理
+func (f *Filter) Cond(cond builder.Cond) *Filter {
+	return f.add(func(func(string) string) builder.Cond { return cond })
+}
+
+func (f *Filter) add(cond condFunc) *Filter {
+	f.conds = append(f.conds, cond)
+	return f
+}
+
+// Asc 追加升序排序列
+func (f *Filter) Asc(columns ...string) *Filter {
+	for _, column := range columns {
+		f.orders = append(f.orders, orderColumn{column: column})
+	}
+	return f
+}
+
+// Desc 追加降序排序列
+func (f *Filter) Desc(columns ...string) *Filter {
+	for _, column := range columns {
+		f.orders = append(f.orders, orderColumn{column: column, desc: true})
+	}
+	return f
+}
+
+// cond 合并后的条件，无条件时返回 nil
+func (f *Filter) cond(quote func(string) string) builder.Cond {
+	if f == nil || len(f.conds) == 0 {
+		return nil
+	}
+	conds := make([]builder.Cond, len(f.conds))
+	for i, c := range f.conds {
+		conds[i] = c(quote)
+	}
+	if len(conds) == 1 {
+		return conds[0]
+	}
+	return builder.And(conds...)
+}
+
+// apply 将条件与排序写入会话
+func (f *Filter) apply(s *xorm.Session, quote func(string) string, withOrder bool) *xorm.Session {
+	if cond := f.cond(quote); cond != nil {
+		s = s.Where(cond)
+	}
+	if withOrder && f != nil {
+		s = applyOrders(s, f.orders)
+	}
+	return s
+}
+
+func applyOrders(s *xorm.Session, orders []orderColumn) *xorm.Session {
+	for _, o := range orders {
+		if o.desc {
+			s = s.Desc(o.column)
+		} else {
+			s = s.Asc(o.column)
+		}
+	}
+	return s
+}
+
+// Page 偏移分页结果
+type Page[T any] struct {
+	Items []T   // 当前页数据
+	Total int64 // 符合条件的总数
+	Page  int   // 页码，从 1 开始
+	Size  int   // 每页条数
+}
+
+// Pages 总页数
+func (p *Page[T]) Pages() int {
+	if p.Size <= 0 {
+		return 0
+	}
+	return int((p.Total + int64(p.Size) - 1) / int64(p.Size))
+}
+
+// CursorPage 游标（keyset）分页结果
+type CursorPage[T any] struct {
+	Items      []T    // 当前页数据
+	NextCursor string // 下一页游标，无更多数据时为空
+	HasMore    bool   // 是否还有下一页
+}
+
+// Repository 基于 xorm 的通用仓储，T 为映射到表的结构体（非指针）。
+// 分页由 xorm 方言生成（Oracle 下为 OFFSET ... FETCH NEXT，需 12c 及以上），游标条件展开为 OR 而非 Oracle 不支持的行值比较
+type Repository[T any] struct {
+	engine  xorm.EngineInterface
+	session *xorm.Session
+}
+
+// NewRepository 创建仓储，engine 可为 *xorm.Engine 或 *xorm.EngineGroup
+func NewRepository[T any](engine xorm.EngineInterface) *Repository[T] {
+	return &Repository[T]{engine: engine}
+}
+
+// WithSession 返回绑定到会话的仓储，用于在 WithTx 等事务中复用
+func (r *Repository[T]) WithSession(session *xorm.Session) *Repository[T] {
+	return &Repository[T]{engine: session.Engine(), session: session}
+}
+
+// sessioner 事务内为绑定的会话，否则为引擎
+func (r *Repository[T]) sessioner() Sessioner {
+	if r.session != nil {
+		return r.session
+	}
+	return r.engine
+}
+
+// newSession 事务内复用绑定的会话，否则创建自动关闭的新会话
+func (r *Repository[T]) newSession(ctx context.Context) *xorm.Session {
+	if r.session != nil {
+		return r.session.Context(ctx)
+	}
+	return r.engine.Context(ctx)
+}
+
+// query 创建会话并写入条件，withOrder 为 false 时忽略排序（计数、更新、删除）
+func (r *Repository[T]) query(ctx context.Context, filter *Filter, withOrder bool) *xorm.Session {
+	return filter.apply(r.newSession(ctx), r.engine.Quote, withOrder)
+}
+
+// Get 按主键查询，不存在时返回 ErrNotFound；复合主键传 schemas.PK
+func (r *Repository[T]) Get(ctx context.Context, id any) (*T, error) {
+	bean := new(T)
+	has, err := r.newSession(ctx).ID(id).Get(bean)
+	if err != nil {
+		return nil, Classify(err)
+	}
+	if !has {
+		return nil, Classify(sql.ErrNoRows)
+	}
+	return bean, nil
+}
+
+// First 按条件与排序查询第一条，不存在时返回 ErrNotFound
+func (r *Repository[T]) First(ctx context.Context, filter *Filter) (*T, error) {
+	bean := new(T)
+	has, err := r.query(ctx, filter, true).Get(bean)
+	if err != nil {
+		return nil, Classify(err)
+	}
+	if !has {
+		return nil, Classify(sql.ErrNoRows)
+	}
+	return bean, nil
+}
+
+// Find 按条件与排序查询全部
+func (r *Repository[T]) Find(ctx context.Context, filter *Filter) ([]T, error) {
+	var items []T
+	if err := r.query(ctx, filter, true).Find(&items); err != nil {
+		return nil, Classify(err)
+	}
+	return items, nil
+}
+
+// Count 按条件计数
+func (r *Repository[T]) Count(ctx context.Context, filter *Filter) (int64, error) {
+	n, err := r.query(ctx, filter, false).Count(new(T))
+	return n, Classify(err)
+}
+
+// Exist 是否存在符合条件的记录
+func (r *Repository[T]) Exist(ctx context.Context, filter *Filter) (bool, error) {
+	has, err := r.query(ctx, filter, false).Exist(new(T))
+	return has, Classify(err)
+}
+
+// Insert 插入一条记录，自增主键回填到 bean
+func (r *Repository[T]) Insert(ctx context.Context, bean *T) error {
+	_, err := r.newSession(ctx).InsertOne(bean)
+	return Classify(err)
+}
+
+// Update 按主键更新。指定 columns 时只更新这些列（含零值），否则只更新非零值字段
+func (r *Repository[T]) Update(ctx context.Context, id any, bean *T, columns ...string) (int64, error) {
+	s := r.newSession(ctx).ID(id)
+	if len(columns) > 0 {
+		s = s.Cols(columns...)
+	}
+	n, err := s.Update(bean)
+	return n, Classify(err)
+}
+
+// UpdateWhere 按条件批量更新，columns 含义同 Update
+func (r *Repository[T]) UpdateWhere(ctx context.Context, filter *Filter, bean *T, columns ...string) (int64, error) {
+	s := r.query(ctx, filter, false)
+	if len(columns) > 0 {
+		s = s.Cols(columns...)
+	}
+	n, err := s.Update(bean)
+	return n, Classify(err)
+}
+
+// Delete 按主键删除
+func (r *Repository[T]) Delete(ctx context.Context, id any) (int64, error) {
+	n, err := r.newSession(ctx).ID(id).Delete(new(T))
+	return n, Classify(err)
+}
+
+// DeleteWhere 按条件批量删除，filter 为空时返回错误以防误删全表
+func (r *Repository[T]) DeleteWhere(ctx context.Context, filter *Filter) (int64, error) {
+	if filter == nil || len(filter.conds) == 0 {
+		return 0, errors.New("database: DeleteWhere requires a condition")
+	}
+	n, err := r.query(ctx, filter, false).Delete(new(T))
+	return n, Classify(err)
+}
+
+// InsertBatch 分批插入，见 BatchInsert
+func (r *Repository[T]) InsertBatch(ctx context.Context, items []*T, opts BatchOptions) (int64, error) {
+	return BatchInsert(ctx, r.sessioner(), items, opts)
+}
+
+// Upsert 分批插入或更新，见 Upsert
+func (r *Repository[T]) Upsert(ctx context.Context, items []*T, opts UpsertOptions) (int64, error) {
+	return Upsert(ctx, r.sessioner(), items, opts)
+}
+
+// Paginate 偏移分页并返回总数。page 从 1 开始，size <= 0 时使用 DefaultPageSize。
+// 深分页在大表上较慢，此时应使用 Scroll
+func (r *Repository[T]) Paginate(ctx context.Context, filter *Filter, page, size int) (*Page[T], error) {
+	page = max(page, 1)
+	if size <= 0 {
+		size = DefaultPageSize
+	}
+	var items []T
+	total, err := r.query(ctx, filter, true).Limit(size, (page-1)*size).FindAndCount(&items)
+	if err != nil {
+		return nil, Classify(err)
+	}
+	return &Page[T]{Items: items, Total: total, Page: page, Size: size}, nil
+}
+
+// Scroll 游标（keyset）分页：按 filter 的排序列（未包含主键时自动追加主键升序保证唯一）取 cursor 之后的 size 条。
+// cursor 为空表示第一页；返回的 NextCursor 为不透明字符串，须与相同的排序一起使用。排序列不支持 NULL 值
+func (r *Repository[T]) Scroll(ctx context.Context, filter *Filter, cursor string, size int) (*CursorPage[T], error) {
+	if size <= 0 {
+		size = DefaultPageSize
+	}
+	table, err := r.engine.TableInfo(new(T))
+	if err != nil {
+		return nil, err
+	}
+	orders, columns, err := keysetOrders(table, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	s := r.query(ctx, filter, false)
+	if cursor != "" {
+		values, err := decodeCursor(cursor, reflect.TypeFor[T](), orders, columns)
+		if err != nil {
+			return nil, err
+		}
+		s = s.And(keysetCond(orders, values, r.engine.Quote))
+	}
+	var items []T
+	if err := applyOrders(s, orders).Limit(size + 1).Find(&items); err != nil {
+		return nil, Classify(err)
+	}
+
+	result := &CursorPage[T]{Items: items}
+	if len(items) > size {
+		result.Items = items[:size]
+		result.HasMore = true
+		if result.NextCursor, err = encodeCursor(&result.Items[size-1], orders, columns); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// keysetOrders 游标分页的排序列，缺少主键时追加主键升序
+func keysetOrders(table *schemas.Table, filter *Filter) ([]orderColumn, []*schemas.Column, error) {
+	var orders []orderColumn
+	if filter != nil {
+		orders = append(orders, filter.orders...)
+	}
+	for _, pk := range table.PrimaryKeys {
+		found := false
+		for _, o := range orders {
+			found = found || strings.EqualFold(o.column, pk)
+		}
+		if !found {
+			orders = append(orders, orderColumn{column: pk})
+		}
+	}
+	if len(orders) == 0 {
+		return nil, nil, fmt.Errorf("database: table %s has no order columns or primary key for keyset pagination", table.Name)
+	}
+	columns := make([]*schemas.Column, len(orders))
+	for i, o := range orders {
+		if columns[i] = table.GetColumn(o.column); columns[i] == nil || len(columns[i].FieldIndex) == 0 {
+			return nil, nil, fmt.Errorf("database: order column %s is not a field of %s", o.column, table.Name)
+		}
+	}
+	return orders, columns, nil
+}
+
+// keysetCond 展开为 (c1 > v1) OR (c1 = v1 AND c2 > v2) ...，兼容不支持行值比较的 Oracle
+func keysetCond(orders []orderColumn, values []any, quote func(string) string) builder.Cond {
+	or := make([]builder.Cond, 0, len(orders))
+	for i, o := range orders {
+		and := make([]builder.Cond, 0, i+1)
+		for j := range i {
+			and = append(and, builder.Eq{quote(orders[j].column): values[j]})
+		}
+		if o.desc {
+			and = append(and, builder.Lt{quote(o.column): values[i]})
+		} else {
+			and = append(and, builder.Gt{quote(o.column): values[i]})
+		}
+		or = append(or, builder.And(and...))
+	}
+	return builder.Or(or...)
+}
+
+// cursorToken 游标内容：排序签名与各排序列的值
+type cursorToken struct {
+	Order  string            `json:"o"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// orderSignature 排序签名，用于校验游标与排序一致
+func orderSignature(orders []orderColumn) string {
+	parts := make([]string, len(orders))
+	for i, o := range orders {
+		parts[i] = strings.ToLower(o.column)
+		if o.desc {
+			parts[i] += " desc"
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func encodeCursor(bean any, orders []orderColumn, columns []*schemas.Column) (string, error) {
+	token := cursorToken{Order: orderSignature(orders), Values: make([]json.RawMessage, len(columns))}
+	for i, col := range columns {
+		v, err := col.ValueOf(bean)
+		if err != nil {
+			return "", err
+		}
+		if token.Values[i], err = json.Marshal(v.Interface()); err != nil {
+			return "", err
+		}
+	}
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor 按字段类型还原游标中的值，保证 int64、time.Time 等精度不丢失
+func decodeCursor(cursor string, typ reflect.Type, orders []orderColumn, columns []*schemas.Column) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	var token cursorToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if token.Order != orderSignature(orders) || len(token.Values) != len(columns) {
+		return nil, fmt.Errorf("%w: order mismatch", ErrInvalidCursor)
+	}
+	values := make([]any, len(columns))
+	for i, col := range columns {
+		v := reflect.New(typ.FieldByIndex(col.FieldIndex).Type)
+		if err := json.Unmarshal(token.Values[i], v.Interface()); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+		values[i] = v.Elem().Interface()
+	}
+	return values, nil
+}
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"xorm.io/xorm"
+)
+
+type repoOrder struct {
+	Id     int64  `xorm:"pk autoincr"`
+	UserId int64  `xorm:"index"`
+	Amount int64  `xorm:"notnull"`
+	Status string `xorm:"varchar(20)"`
+}
+
+func newOrderRepository(t *testing.T) (*xorm.Engine, *Repository[repoOrder]) {
+	t.Helper()
+	engine := newSQLiteEngine(t)
+	if err := engine.Sync(new(repoOrder)); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewRepository[repoOrder](engine)
+	ctx := context.Background()
+	for i := range 25 {
+		status := "paid"
+		if i%5 == 0 {
+			status = "canceled"
+		}
+		// Amount 有重复值，用于验证游标分页的主键兜底排序
+		if err := repo.Insert(ctx, &repoOrder{UserId: int64(i % 3), Amount: int64(i % 7 * 100), Status: status}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return engine, repo
+}
+
+func TestRepository_CRUD(t *testing.T) {
+	_, repo := newOrderRepository(t)
+	ctx := context.Background()
+
+	order, err := repo.Get(ctx, 1)
+	if err != nil || order.Id != 1 {
+		t.Fatalf("Get 失败: %+v %v", order, err)
+	}
+	if _, err := repo.Get(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("不存在时应返回 ErrNotFound: %v", err)
+	}
+
+	if n, err := repo.Update(ctx, 1, &repoOrder{Amount: 0, Status: "refund"}, "amount", "status"); err != nil || n != 1 {
+		t.Fatalf("Update 失败: %d %v", n, err)
+	}
+	if order, _ = repo.Get(ctx, 1); order.Amount != 0 || order.Status != "refund" {
+		t.Errorf("指定列应更新零值: %+v", order)
+	}
+
+	filter := NewFilter().Eq("status", "paid").Gte("amount", 300).In("user_id", 0, 1)
+	items, err := repo.Find(ctx, filter.Desc("amount"))
+	if err != nil || len(items) == 0 {
+		t.Fatalf("Find 失败: %v %v", items, err)
+	}
+	for i, o := range items {
+		if o.Status != "paid" || o.Amount < 300 || o.UserId == 2 || (i > 0 && o.Amount > items[i-1].Amount) {
+			t.Errorf("结果不符合条件或排序: %+v", o)
+		}
+	}
+	n, _ := repo.Count(ctx, NewFilter().Eq("status", "paid").Gte("amount", 300).In("user_id", 0, 1))
+	if n != int64(len(items)) {
+		t.Errorf("Count=%d want=%d", n, len(items))
+	}
+
+	either := NewFilter().Or(NewFilter().Eq("id", 2), NewFilter().Eq("id", 3))
+	if n, _ := repo.Count(ctx, either); n != 2 {
+		t.Errorf("Or 条件计数=%d", n)
+	}
+	if _, err := repo.DeleteWhere(ctx, nil); err == nil {
+		t.Error("无条件批量删除应返回错误")
+	}
+	if n, err := repo.DeleteWhere(ctx, NewFilter().Eq("status", "canceled")); err != nil || n != 4 { // id=1 已改为 refund
+		t.Errorf("DeleteWhere=%d %v", n, err)
+	}
+	if has, _ := repo.Exist(ctx, NewFilter().Eq("status", "canceled")); has {
+		t.Error("删除后不应存在")
+	}
+}
+
+func TestRepository_WithSession(t *testing.T) {
+	engine, repo := newOrderRepository(t)
+	ctx := context.Background()
+	err := WithTx(ctx, engine, func(s *xorm.Session) error {
+		txRepo := repo.WithSession(s)
+		if _, err := txRepo.Delete(ctx, 1); err != nil {
+			return err
+		}
+		return errors.New("回滚")
+	})
+	if err == nil {
+		t.Fatal("应返回业务错误")
+	}
+	if _, err := repo.Get(ctx, 1); err != nil {
+		t.Errorf("事务回滚后记录应仍存在: %v", err)
+	}
+}
+
+func TestRepository_Paginate(t *testing.T) {
+	_, repo := newOrderRepository(t)
+	page, err := repo.Paginate(context.Background(), NewFilter().Eq("status", "paid").Asc("id"), 3, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 20 || page.Pages() != 3 || len(page.Items) != 4 {
+		t.Errorf("分页结果不符: total=%d pages=%d items=%d", page.Total, page.Pages(), len(page.Items))
+	}
+}
+
+func TestRepository_Scroll(t *testing.T) {
+	_, repo := newOrderRepository(t)
+	ctx := context.Background()
+	newFilter := func() *Filter { return NewFilter().Ne("status", "canceled").Desc("amount") }
+
+	seen := map[int64]bool{}
+	var last *repoOrder
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("游标分页未结束")
+		}
+		page, err := repo.Scroll(ctx, newFilter(), cursor, 6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range page.Items {
+			if seen[o.Id] {
+				t.Errorf("记录重复: %d", o.Id)
+			}
+			seen[o.Id] = true
+			if last != nil && (o.Amount > last.Amount || (o.Amount == last.Amount && o.Id < last.Id)) {
+				t.Errorf("排序不符: %+v 在 %+v 之后", o, last)
+			}
+			last = &o
+		}
+		if !page.HasMore {
+			if page.NextCursor != "" {
+				t.Error("最后一页不应返回游标")
+			}
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if len(seen) != 20 {
+		t.Errorf("游标分页应覆盖全部 20 条, got=%d", len(seen))
+	}
+
+	if _, err := repo.Scroll(ctx, NewFilter().Asc("amount"), cursor, 6); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("排序不一致时应返回 ErrInvalidCursor: %v", err)
+	}
+	if _, err := repo.Scroll(ctx, newFilter(), "not-a-cursor!", 6); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("非法游标应返回 ErrInvalidCursor: %v", err)
+	}
+}
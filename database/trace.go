@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/muchinfo/mtp2-common-lib/logger"
+	"go.uber.org/zap"
+	"xorm.io/xorm"
+	"xorm.io/xorm/contexts"
+)
+
+// SQLSpan 一次 SQL 执行的 span。OnStart 时 Duration、Rows、Err 尚未填充
+type SQLSpan struct {
+	TraceID   string        // 来自 logger.WithTraceID，未设置时为空
+	RequestID string        // 来自 logger.WithRequestID，未设置时为空
+	SpanID    string        // 16 位十六进制
+	SQL       string        // 原始 SQL
+	Args      []any         // 绑定参数（已按 Config.MaskArg 脱敏，默认 MaskArg）
+	Start     time.Time     // 开始时间
+	Duration  time.Duration // 执行耗时
+	Rows      int64         // 影响行数，查询语句或无法获取时为 -1
+	Err       error         // 执行错误
+}
+
+// SpanExporter 接收 SQL span 的开始/结束事件，可对接 OpenTelemetry 等链路追踪系统。
+// OnStart 返回的 context 会传给驱动执行 SQL，并在 OnEnd 时原样传回
+type SpanExporter interface {
+	OnStart(ctx context.Context, span *SQLSpan) context.Context
+	OnEnd(ctx context.Context, span *SQLSpan)
+}
+
+type ctxKeySQLSpan struct{}
+
+// tracingHook 将每条 SQL 的开始/结束转发给 SpanExporter
+type tracingHook struct {
+	exporter SpanExporter
+	mask     func(arg any) any // 参数脱敏，为 nil 时使用 MaskArg
+}
+
+// NewTracingHook 创建 SQL 链路追踪 hook
+func NewTracingHook(exporter SpanExporter) contexts.Hook {
+	return &tracingHook{exporter: exporter}
+}
+
+// UseTracing 为引擎安装 SQL 链路追踪 hook。
+// 与熔断器同时使用时应在 UseCircuitBreaker 之后安装，被熔断拒绝的 SQL 不产生 span
+func UseTracing(engine *xorm.Engine, exporter SpanExporter) {
+	engine.AddHook(NewTracingHook(exporter))
+}
+
+func (h *tracingHook) maskArg() func(arg any) any {
+	if h.mask == nil {
+		return MaskArg
+	}
+	return h.mask
+}
+
+func (h *tracingHook) BeforeProcess(c *contexts.ContextHook) (context.Context, error) {
+	span := &SQLSpan{
+		TraceID:   logger.TraceID(c.Ctx),
+		RequestID: logger.RequestID(c.Ctx),
+		SpanID:    newSpanID(),
+		SQL:       c.SQL,
+		Args:      maskArgs(c.Args, h.maskArg()),
+		Start:     time.Now(),
+		Rows:      -1,
+	}
+	ctx := h.exporter.OnStart(c.Ctx, span)
+	if ctx == nil {
+		ctx = c.Ctx
+	}
+	// 多个 hook 时 xorm 只采用最后一个 hook 返回的 ctx，写回 c.Ctx 以免丢失
+	c.Ctx = context.WithValue(ctx, ctxKeySQLSpan{}, span)
+	return c.Ctx, nil
+}
+
+func (h *tracingHook) AfterProcess(c *contexts.ContextHook) error {
+	span, ok := c.Ctx.Value(ctxKeySQLSpan{}).(*SQLSpan)
+	if !ok {
+		return nil
+	}
+	span.Duration = c.ExecuteTime
+	span.Err = c.Err
+	if c.Result != nil {
+		if rows, err := c.Result.RowsAffected(); err == nil {
+			span.Rows = rows
+		}
+	}
+	h.exporter.OnEnd(c.Ctx, span)
+	return nil
+}
+
+// newSpanID 生成 16 位十六进制 span ID
+func newSpanID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// ZapSpanExporter 将 SQL span 事件以 Debug 级别输出到 zap，便于无追踪系统时按 trace_id 排查
+type ZapSpanExporter struct {
+	logger *zap.Logger
+}
+
+// NewZapSpanExporter 创建输出到 zap 的 SpanExporter
+func NewZapSpanExporter(logger *zap.Logger) *ZapSpanExporter {
+	return &ZapSpanExporter{logger: logger}
+}
+
+// OnStart 实现 SpanExporter
+func (e *ZapSpanExporter) OnStart(ctx context.Context, span *SQLSpan) context.Context {
+	e.logger.Debug("[XORM] SQL开始", append(spanFields(span), zap.String("sql", span.SQL), zap.Any("args", span.Args))...)
+	return ctx
+}
+
+// OnEnd 实现 SpanExporter
+func (e *ZapSpanExporter) OnEnd(ctx context.Context, span *SQLSpan) {
+	fields := append(spanFields(span), zap.Duration("cost", span.Duration))
+	if span.Rows >= 0 {
+		fields = append(fields, zap.Int64("rows", span.Rows))
+	}
+	if span.Err != nil {
+		fields = append(fields, zap.Error(span.Err))
+	}
+	e.logger.Debug("[XORM] SQL结束", fields...)
+}
+
+func spanFields(span *SQLSpan) []zap.Field {
+	fields := []zap.Field{zap.String("span_id", span.SpanID)}
+	if span.TraceID != "" {
+		fields = append(fields, zap.String("trace_id", span.TraceID))
+	}
+	if span.RequestID != "" {
+		fields = append(fields, zap.String("request_id", span.RequestID))
+	}
+	return fields
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/muchinfo/mtp2-common-lib/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"xorm.io/xorm"
+)
+
+type recordExporter struct {
+	mutex  sync.Mutex
+	starts []SQLSpan
+	ends   []SQLSpan
+}
+
+func (e *recordExporter) OnStart(ctx context.Context, span *SQLSpan) context.Context {
+	e.mutex.Lock()
+	e.starts = append(e.starts, *span)
+	e.mutex.Unlock()
+	return ctx
+}
+
+func (e *recordExporter) OnEnd(ctx context.Context, span *SQLSpan) {
+	e.mutex.Lock()
+	e.ends = append(e.ends, *span)
+	e.mutex.Unlock()
+}
+
+func TestTracing_TraceIDInLogsAndSpans(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	exporter := &recordExporter{}
+	engine, err := NewEngine(Config{
+		Driver:  "sqlite",
+		DSN:     filepath.Join(t.TempDir(), "trace.db"),
+		Options: Options{ShowSQL: true},
+		Logger:  zap.New(core),
+		Tracer:  exporter,
+		Breaker: NewCircuitBreaker(5),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	if err := AutoMigrate(engine); err != nil {
+		t.Fatal(err)
+	}
+	logs.TakeAll()
+	exporter.starts, exporter.ends = nil, nil
+
+	ctx := logger.WithRequestID(logger.WithTraceID(context.Background(), "trace-42"), "req-7")
+	if _, err := engine.Context(ctx).Insert(&User{Name: "王五六", Age: 20}); err != nil {
+		t.Fatal(err)
+	}
+	err = WithTx(ctx, engine, func(s *xorm.Session) error {
+		_, err := s.Where("name = ?", "王五六").Get(&User{})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := logs.FilterMessage("[SQL]").All()
+	if len(entries) == 0 {
+		t.Fatal("ShowSQL 时应输出 SQL 日志")
+	}
+	for _, e := range entries {
+		fields := e.ContextMap()
+		if fields["trace_id"] != "trace-42" || fields["request_id"] != "req-7" {
+			t.Errorf("SQL 日志缺少链路 ID: %v", fields)
+		}
+	}
+
+	if len(exporter.starts) != len(exporter.ends) || len(exporter.ends) < 4 {
+		t.Fatalf("span 开始/结束事件不匹配: start=%d end=%d", len(exporter.starts), len(exporter.ends))
+	}
+	for i, span := range exporter.ends {
+		if span.TraceID != "trace-42" || span.RequestID != "req-7" || span.SpanID != exporter.starts[i].SpanID {
+			t.Errorf("span[%d] 不符: %+v", i, span)
+		}
+	}
+	if insert := exporter.ends[0]; insert.Rows != 1 || insert.Args[0] != "王***六" {
+		t.Errorf("INSERT span 应记录影响行数与脱敏参数: %+v", insert)
+	}
+}
+
+func TestZapSpanExporter(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	engine := newSQLiteEngine(t)
+	UseTracing(engine, NewZapSpanExporter(zap.New(core)))
+
+	engine.Context(logger.WithTraceID(context.Background(), "trace-1")).Exec("SELECT * FROM no_such_table")
+	end := logs.FilterMessage("[XORM] SQL结束").All()
+	if logs.FilterMessage("[XORM] SQL开始").Len() != 1 || len(end) != 1 {
+		t.Fatalf("应输出一对 span 事件: %v", logs.All())
+	}
+	fields := end[0].ContextMap()
+	if fields["trace_id"] != "trace-1" || fields["error"] == nil {
+		t.Errorf("结束事件应包含链路 ID 与错误: %v", fields)
+	}
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"go.uber.org/zap"
+	"xorm.io/xorm"
+)
+
+// ErrCommitUnknown 提交阶段出错，事务可能已在数据库中生效，不会自动重试，调用方需自行核对结果
+var ErrCommitUnknown = errors.New("database: commit result unknown")
+
+// TxOptions 事务重试选项
+type TxOptions struct {
+	MaxRetries  int                  // 最大重试次数（不含首次执行），默认 3，负数表示不重试
+	MinBackoff  time.Duration        // 首次重试等待时间，默认 100 毫秒，之后按 2 倍递增并加随机抖动
+	MaxBackoff  time.Duration        // 最大等待时间，默认 2 秒
+	IsRetryable func(err error) bool // 判断错误是否可重试，默认 IsTransient
+	Logger      *zap.Logger          // 日志，允许为 nil
+}
+
+func (o TxOptions) withDefaults() TxOptions {
+	if o.MaxRetries == 0 {
+		o.MaxRetries = 3
+	}
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = 100 * time.Millisecond
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 2 * time.Second
+	}
+	if o.IsRetryable == nil {
+		o.IsRetryable = IsTransient
+	}
+	return o
+}
+
+// WithTx 在事务中执行 fn：fn 返回 nil 时提交，返回错误或 panic 时回滚（panic 回滚后继续向上抛出），
+// 死锁、连接断开、序列化失败等瞬时错误按默认选项重试整个 fn，因此 fn 应只包含数据库操作，不应有外部副作用。
+// 提交阶段出错时不重试，返回匹配 ErrCommitUnknown 的错误。
+// 返回的错误已经过 Classify，可直接使用 errors.Is(err, ErrDuplicateKey) 等判断
+//
+//	err := database.WithTx(ctx, engine, func(s *xorm.Session) error {
+//	    if _, err := s.Insert(&order); err != nil {
+//	        return err
+//	    }
+//	    _, err := s.ID(acc.Id).Decr("balance", order.Amount).Update(new(Account))
+//	    return err
+//	})
+func WithTx(ctx context.Context, engine xorm.EngineInterface, fn func(*xorm.Session) error) error {
+	return WithTxOptions(ctx, engine, TxOptions{}, fn)
+}
+
+// WithTxOptions 按指定选项在事务中执行 fn，见 WithTx
+func WithTxOptions(ctx context.Context, engine xorm.EngineInterface, opts TxOptions, fn func(*xorm.Session) error) error {
+	opts = opts.withDefaults()
+	backoff := opts.MinBackoff
+	for attempt := 0; ; attempt++ {
+		err := runTx(ctx, engine, fn)
+		if err == nil || errors.Is(err, ErrCommitUnknown) || attempt >= opts.MaxRetries || !opts.IsRetryable(err) {
+			return Classify(err)
+		}
+
+		wait := backoff/2 + rand.N(backoff/2+1)
+		if opts.Logger != nil {
+			opts.Logger.Warn("[XORM] 事务遇到瞬时错误，准备重试",
+				zap.Int("attempt", attempt+1), zap.Duration("wait", wait), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(Classify(err), ctx.Err())
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, opts.MaxBackoff)
+	}
+}
+
+// runTx 执行一次事务
+func runTx(ctx context.Context, engine xorm.EngineInterface, fn func(*xorm.Session) error) (err error) {
+	session := engine.NewSession()
+	defer session.Close()
+	session.Context(ctx)
+	if err := session.Begin(); err != nil {
+		return err
+	}
+
+	finished := false
+	defer func() {
+		if finished {
+			return
+		}
+		if p := recover(); p != nil {
+			session.Rollback()
+			panic(p)
+		}
+		if rerr := session.Rollback(); rerr != nil {
+			err = errors.Join(err, rerr)
+		}
+	}()
+
+	if err = fn(session); err != nil {
+		return err
+	}
+	// 提交失败时事务可能已生效（如提交后连接断开），不再回滚也不重试
+	finished = true
+	if err = session.Commit(); err != nil {
+		return fmt.Errorf("%w: %w", ErrCommitUnknown, err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"xorm.io/xorm"
+)
+
+// oraTestErr 模拟 godror.OraErr
+type oraTestErr struct{ code int }
+
+func (e *oraTestErr) Code() int     { return e.code }
+func (e *oraTestErr) Error() string { return fmt.Sprintf("ORA-%05d: test", e.code) }
+
+func countUsers(t *testing.T, engine *xorm.Engine) int64 {
+	t.Helper()
+	n, err := engine.Count(new(User))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestWithTx_CommitRollbackPanic(t *testing.T) {
+	engine := newSQLiteEngine(t)
+	if err := AutoMigrate(engine); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	err := WithTx(ctx, engine, func(s *xorm.Session) error {
+		_, err := s.Insert(&User{Name: "提交", Age: 1})
+		return err
+	})
+	if err != nil || countUsers(t, engine) != 1 {
+		t.Fatalf("提交失败: err=%v", err)
+	}
+
+	bizErr := errors.New("余额不足")
+	err = WithTx(ctx, engine, func(s *xorm.Session) error {
+		if _, err := s.Insert(&User{Name: "回滚", Age: 2}); err != nil {
+			return err
+		}
+		return bizErr
+	})
+	if !errors.Is(err, bizErr) || countUsers(t, engine) != 1 {
+		t.Fatalf("业务错误应回滚并原样返回: err=%v", err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic 应继续向上抛出")
+			}
+		}()
+		WithTx(ctx, engine, func(s *xorm.Session) error {
+			s.Insert(&User{Name: "panic", Age: 3})
+			panic("boom")
+		})
+	}()
+	if countUsers(t, engine) != 1 {
+		t.Error("panic 后事务应回滚")
+	}
+}
+
+func TestWithTx_RetryTransient(t *testing.T) {
+	engine := newSQLiteEngine(t)
+	if err := AutoMigrate(engine); err != nil {
+		t.Fatal(err)
+	}
+	opts := TxOptions{MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+	calls := 0
+	err := WithTxOptions(context.Background(), engine, opts, func(s *xorm.Session) error {
+		calls++
+		if _, err := s.Insert(&User{Name: "重试", Age: calls}); err != nil {
+			return err
+		}
+		if calls < 3 {
+			return fmt.Errorf("insert: %w", &oraTestErr{code: 60})
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("死锁应重试后成功: calls=%d err=%v", calls, err)
+	}
+	if n := countUsers(t, engine); n != 1 {
+		t.Errorf("失败的尝试应回滚, count=%d", n)
+	}
+
+	calls = 0
+	err = WithTxOptions(context.Background(), engine, opts, func(s *xorm.Session) error {
+		calls++
+		return &oraTestErr{code: 1}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("唯一约束冲突不应重试: calls=%d err=%v", calls, err)
+	}
+
+	calls = 0
+	opts.MaxRetries = 2
+	WithTxOptions(context.Background(), engine, opts, func(s *xorm.Session) error {
+		calls++
+		return driver.ErrBadConn
+	})
+	if calls != 3 {
+		t.Errorf("重试次数耗尽后应返回错误: calls=%d", calls)
+	}
+}
+
+func TestWithTx_CommitFailure(t *testing.T) {
+	engine := newSQLiteEngine(t)
+	// 延迟检查的外键在提交时才校验，用于模拟提交失败
+	engine.SetMaxOpenConns(1)
+	for _, sql := range []string{
+		"PRAGMA foreign_keys = ON",
+		"CREATE TABLE parent (id INTEGER PRIMARY KEY)",
+		"CREATE TABLE child (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parent(id) DEFERRABLE INITIALLY DEFERRED)",
+	} {
+		if _, err := engine.Exec(sql); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	calls := 0
+	opts := TxOptions{MinBackoff: time.Millisecond, IsRetryable: func(error) bool { return true }}
+	err := WithTxOptions(context.Background(), engine, opts, func(s *xorm.Session) error {
+		calls++
+		_, err := s.Exec("INSERT INTO child (id, parent_id) VALUES (1, 99)")
+		return err
+	})
+	if !errors.Is(err, ErrCommitUnknown) {
+		t.Fatalf("提交失败应返回 ErrCommitUnknown: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("提交失败不应重试: calls=%d", calls)
+	}
+	if IsTransient(err) {
+		t.Error("提交结果未知不应视为瞬时错误")
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("ORA-03113: end-of-file on communication channel"), true},
+		{fmt.Errorf("query: %w", &oraTestErr{code: 8177}), true},
+		{&oraTestErr{code: 1}, false},
+		{driver.ErrBadConn, true},
+		{errors.New("no such table"), false},
+		{fmt.Errorf("%w: %w", ErrCommitUnknown, driver.ErrBadConn), false},
+	}
+	for _, c := range cases {
+		if got := IsTransient(c.err); got != c.want {
+			t.Errorf("IsTransient(%v)=%v want=%v", c.err, got, c.want)
+		}
+	}
+}
@@ -4,134 +4,115 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
-	"sync/atomic"
 	"time"
 
 	_ "github.com/godror/godror"
+	"github.com/muchinfo/mtp2-common-lib/logger"
 	"go.uber.org/zap"
 	"xorm.io/xorm"
 	"xorm.io/xorm/log"
 )
 
 // NewOracleEngine 创建 Oracle 数据库引擎，支持 zap.Logger 注入、连接监控、慢 SQL 统计、熔断
+// 连接池与日志使用默认选项，需自定义时使用 NewOracleEngineWithOptions
 func NewOracleEngine(dsn string, logger *zap.Logger, slowThreshold time.Duration, breaker *CircuitBreaker) (*xorm.Engine, error) {
-	engine, err := xorm.NewEngine("oracle", dsn)
-	if err != nil {
-		return nil, err
-	}
-	// 连接池配置
-	engine.SetMaxOpenConns(20)
-	engine.SetMaxIdleConns(5)
-	engine.SetConnMaxLifetime(30 * time.Minute)
-
-	// 日志对接 zap，慢 SQL 统计
-	if logger != nil {
-		engine.SetLogger(&ZapXormLogger{
-			logger:        logger,
-			slowThreshold: slowThreshold,
-		})
-	} else {
-		engine.SetLogger(log.NewSimpleLogger(os.Stdout))
-	}
-	engine.ShowSQL(true)
-
-	// 健康检查
-	if err := engine.Ping(); err != nil {
-		for i := 0; i < 3; i++ {
-			time.Sleep(time.Second * 2)
-			if err = engine.Ping(); err == nil {
-				break
-			}
-		}
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	// 连接监控与熔断
-	if breaker != nil {
-		go monitorConnection(engine, breaker, logger)
-	}
+	opts := DefaultOptions()
+	opts.SlowThreshold = slowThreshold
+	return NewOracleEngineWithOptions(dsn, opts, logger, breaker)
+}
 
-	return engine, nil
+// NewOracleEngineWithOptions 按选项创建 Oracle 数据库引擎
+func NewOracleEngineWithOptions(dsn string, opts Options, logger *zap.Logger, breaker *CircuitBreaker) (*xorm.Engine, error) {
+	return NewEngine(Config{
+		Driver:  "oracle",
+		DSN:     dsn,
+		Options: opts,
+		Logger:  logger,
+		Breaker: breaker,
+	})
 }
 
-// monitorConnection 定时健康检查，触发熔断
-func monitorConnection(engine *xorm.Engine, breaker *CircuitBreaker, logger *zap.Logger) {
-	ticker := time.NewTicker(10 * time.Second)
+// monitorInterval 连接监控的健康检查间隔
+const monitorInterval = 10 * time.Second
+
+// monitorConnection 定时健康检查，触发熔断；熔断打开期间跳过检查，冷却结束后以 Ping 作为试探请求。
+// 引擎关闭后退出
+func monitorConnection(engine *xorm.Engine, breaker *CircuitBreaker, logger *zap.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	for range ticker.C {
-		err := engine.Ping()
+		if engineClosed(engine) {
+			return
+		}
+		gen, err := breaker.allow()
 		if err != nil {
-			breaker.Fail()
-			if logger != nil {
-				logger.Error("[XORM] 数据库健康检查失败，熔断计数+1", zap.Error(err))
-			}
-		} else {
-			breaker.Success()
+			continue
+		}
+		err = engine.Ping()
+		breaker.record(gen, err != nil)
+		if err != nil && logger != nil {
+			logger.Error("[XORM] 数据库健康检查失败，熔断计数+1", zap.Error(err), zap.Stringer("state", breaker.State()))
 		}
 	}
 }
 
-// CircuitBreaker 简单熔断器
-type CircuitBreaker struct {
-	failCount int32
-	threshold int32
-	open      int32
+// engineClosed 判断引擎是否已 Close：以已取消的 context Ping，
+// database/sql 先检查是否已关闭，未关闭时在获取连接前返回 context.Canceled，不产生网络请求
+func engineClosed(engine *xorm.Engine) bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := engine.DB().PingContext(ctx)
+	return err != nil && !errors.Is(err, context.Canceled)
 }
 
-func NewCircuitBreaker(threshold int32) *CircuitBreaker {
-	return &CircuitBreaker{threshold: threshold}
+// ZapXormLogger 实现 xorm log.ContextLogger 接口，输出到 zap；
+// ShowSQL 时每条 SQL 日志附带 context 中的 trace_id / request_id（见 logger.WithTraceID）
+type ZapXormLogger struct {
+	logger  *zap.Logger
+	level   log.LogLevel
+	showSQL bool
+	mask    func(arg any) any // 参数脱敏，为 nil 时使用 MaskArg
 }
 
-func (b *CircuitBreaker) Fail() {
-	atomic.AddInt32(&b.failCount, 1)
-	if atomic.LoadInt32(&b.failCount) >= b.threshold {
-		atomic.StoreInt32(&b.open, 1)
+func (z *ZapXormLogger) Debug(v ...any) {
+	if z.level <= log.LOG_DEBUG {
+		z.logger.Debug(sprint(v...))
 	}
 }
-
-func (b *CircuitBreaker) Success() {
-	atomic.StoreInt32(&b.failCount, 0)
-	atomic.StoreInt32(&b.open, 0)
-}
-
-func (b *CircuitBreaker) IsOpen() bool {
-	return atomic.LoadInt32(&b.open) == 1
-}
-
-func (b *CircuitBreaker) Check() error {
-	if b.IsOpen() {
-		return errors.New("circuit breaker open: 数据库连接异常")
+func (z *ZapXormLogger) Debugf(format string, v ...any) {
+	if z.level <= log.LOG_DEBUG {
+		z.logger.Debug(fmt.Sprintf(format, v...))
 	}
-	return nil
-}
-
-// ZapXormLogger 实现 xorm log.Logger 接口，输出到 zap
-
-type ZapXormLogger struct {
-	logger        *zap.Logger
-	level         log.LogLevel
-	showSQL       bool
-	slowThreshold time.Duration
 }
-
-func (z *ZapXormLogger) Debug(v ...any) { z.logger.Debug(sprint(v...)) }
-func (z *ZapXormLogger) Debugf(format string, v ...any) {
-	z.logger.Debug(fmt.Sprintf(format, v...))
+func (z *ZapXormLogger) Error(v ...any) {
+	if z.level <= log.LOG_ERR {
+		z.logger.Error(sprint(v...))
+	}
 }
-func (z *ZapXormLogger) Error(v ...any) { z.logger.Error(sprint(v...)) }
 func (z *ZapXormLogger) Errorf(format string, v ...any) {
-	z.logger.Error(fmt.Sprintf(format, v...))
+	if z.level <= log.LOG_ERR {
+		z.logger.Error(fmt.Sprintf(format, v...))
+	}
+}
+func (z *ZapXormLogger) Info(v ...any) {
+	if z.level <= log.LOG_INFO {
+		z.logger.Info(sprint(v...))
+	}
 }
-func (z *ZapXormLogger) Info(v ...any) { z.logger.Info(sprint(v...)) }
 func (z *ZapXormLogger) Infof(format string, v ...any) {
-	z.logger.Info(fmt.Sprintf(format, v...))
+	if z.level <= log.LOG_INFO {
+		z.logger.Info(fmt.Sprintf(format, v...))
+	}
+}
+func (z *ZapXormLogger) Warn(v ...any) {
+	if z.level <= log.LOG_WARNING {
+		z.logger.Warn(sprint(v...))
+	}
 }
-func (z *ZapXormLogger) Warn(v ...any) { z.logger.Warn(sprint(v...)) }
 func (z *ZapXormLogger) Warnf(format string, v ...any) {
-	z.logger.Warn(fmt.Sprintf(format, v...))
+	if z.level <= log.LOG_WARNING {
+		z.logger.Warn(fmt.Sprintf(format, v...))
+	}
 }
 func (z *ZapXormLogger) Level() log.LogLevel     { return z.level }
 func (z *ZapXormLogger) SetLevel(l log.LogLevel) { z.level = l }
@@ -142,18 +123,30 @@ func (z *ZapXormLogger) ShowSQL(show ...bool) {
 }
 func (z *ZapXormLogger) IsShowSQL() bool { return z.showSQL }
 
-// 慢 SQL 统计
-type ctxKeyStartTime struct{}
+// BeforeSQL 实现 log.SQLLogger，SQL 在执行结束后由 AfterSQL 统一输出
+func (z *ZapXormLogger) BeforeSQL(ctx log.LogContext) {}
 
-func (z *ZapXormLogger) BeforeSQL(ctx context.Context, sql string, args ...any) context.Context {
-	return context.WithValue(ctx, ctxKeyStartTime{}, time.Now())
-}
-func (z *ZapXormLogger) AfterSQL(ctx context.Context, sql string, args ...any) {
-	start, _ := ctx.Value(ctxKeyStartTime{}).(time.Time)
-	cost := time.Since(start)
-	if z.slowThreshold > 0 && cost > z.slowThreshold {
-		z.logger.Warn("[XORM] 慢SQL", zap.String("sql", sql), zap.Duration("cost", cost))
+// AfterSQL 实现 log.SQLLogger，输出 SQL、脱敏后的参数（见 Config.MaskArg）、耗时与链路 ID，仅在 ShowSQL 时由 xorm 调用
+func (z *ZapXormLogger) AfterSQL(ctx log.LogContext) {
+	if z.level > log.LOG_INFO {
+		return
+	}
+	mask := z.mask
+	if mask == nil {
+		mask = MaskArg
 	}
+	fields := append(logger.ContextFields(ctx.Ctx),
+		zap.String("sql", ctx.SQL),
+		zap.Any("args", maskArgs(ctx.Args, mask)),
+		zap.Duration("cost", ctx.ExecuteTime),
+	)
+	if session, ok := ctx.Ctx.Value(log.SessionIDKey).(string); ok {
+		fields = append(fields, zap.String("session", session))
+	}
+	if ctx.Err != nil {
+		fields = append(fields, zap.Error(ctx.Err))
+	}
+	z.logger.Info("[SQL]", fields...)
 }
 
 func sprint(v ...any) string {
@@ -165,20 +158,9 @@ func Ping(engine *xorm.Engine) error {
 	return engine.Ping()
 }
 
-// TryReconnect 尝试断线重连
+// TryReconnect 以默认选项尝试断线重连 Oracle，需保留日志、连接池与熔断设置时使用 TryReconnectWithConfig
 func TryReconnect(engine **xorm.Engine, dsn string, maxRetry int) error {
-	var err error
-	for range maxRetry {
-		if *engine != nil {
-			(*engine).Close()
-		}
-		*engine, err = xorm.NewEngine("oracle", dsn)
-		if err == nil && (*engine).Ping() == nil {
-			return nil
-		}
-		time.Sleep(time.Second * 2)
-	}
-	return err
+	return TryReconnectWithConfig(engine, Config{Driver: "oracle", DSN: dsn}, maxRetry)
 }
 
 // User 示例实体
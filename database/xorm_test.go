@@ -1,13 +1,43 @@
 package database
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"xorm.io/xorm/log"
 )
 
+func TestZapXormLogger_MaskArgs(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	z := &ZapXormLogger{logger: zap.New(core)}
+	z.AfterSQL(log.LogContext{
+		Ctx:  context.Background(),
+		SQL:  "SELECT * FROM users WHERE phone = ? AND age = ?",
+		Args: []any{"13800138000", 20},
+	})
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("应输出一条 SQL 日志, got=%d", len(entries))
+	}
+	args, _ := entries[0].ContextMap()["args"].([]any)
+	if len(args) != 2 || args[0] != "1***0" || args[1] != "***" {
+		t.Errorf("SQL 日志参数应脱敏: %v", entries[0].ContextMap()["args"])
+	}
+
+	// 显式替换脱敏函数后保留数值参数
+	logs.TakeAll()
+	z.mask = MaskArgKeepScalars
+	z.AfterSQL(log.LogContext{Ctx: context.Background(), SQL: "SELECT 1", Args: []any{"13800138000", 20}})
+	args, _ = logs.All()[0].ContextMap()["args"].([]any)
+	if len(args) != 2 || args[0] != "1***0" || args[1] != 20 {
+		t.Errorf("MaskArgKeepScalars 应保留数值参数: %v", args)
+	}
+}
+
 func TestOracleEngineAndCRUD(t *testing.T) {
 	dsn := os.Getenv("ORACLE_DSN")
 	if dsn == "" {
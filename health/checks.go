@@ -0,0 +1,48 @@
+package health
+
+import (
+	"context"
+	"errors"
+)
+
+// Pinger 无 context 的 Ping，如 *redis.RedisClient、*xorm.Engine
+type Pinger interface {
+	Ping() error
+}
+
+// ContextPinger 支持 context 的 Ping，如 *xorm.Engine、*sql.DB
+type ContextPinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingCheck 以 Ping 作为检查，超时由 Health 控制
+func PingCheck(p Pinger) CheckFunc {
+	return func(context.Context) error {
+		return p.Ping()
+	}
+}
+
+// PingContextCheck 以 PingContext 作为检查，超时会传递给驱动
+func PingContextCheck(p ContextPinger) CheckFunc {
+	return p.PingContext
+}
+
+// ConnectionCheck 检查连接状态，如 *mq.RabbitMQClient
+func ConnectionCheck(c interface{ IsConnected() bool }) CheckFunc {
+	return func(context.Context) error {
+		if !c.IsConnected() {
+			return errors.New("not connected")
+		}
+		return nil
+	}
+}
+
+// RunningCheck 检查服务是否在运行，如 *websocket.WSServer、*socket.TCPServer
+func RunningCheck(s interface{ IsRunning() bool }) CheckFunc {
+	return func(context.Context) error {
+		if !s.IsRunning() {
+			return errors.New("not running")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	h := New(Config{CacheTTL: -1})
+	var dbErr error
+	h.Register(Check{Name: "database", Critical: true, Check: func(context.Context) error { return dbErr }})
+	server := httptest.NewServer(h.Handler())
+	defer server.Close()
+
+	get := func(path string) (int, map[string]any) {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body map[string]any
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	code, body := get("/readyz")
+	if code != http.StatusOK || body["status"] != "up" {
+		t.Errorf("就绪检查=%d %v", code, body)
+	}
+	checks, _ := body["checks"].([]any)
+	if len(checks) != 1 || checks[0].(map[string]any)["duration"] == nil {
+		t.Errorf("报告应包含检查项与耗时: %v", body)
+	}
+
+	dbErr = errors.New("ORA-03113")
+	if code, body = get("/healthz"); code != http.StatusServiceUnavailable || body["status"] != "down" {
+		t.Errorf("关键检查失败应返回 503: %d %v", code, body)
+	}
+	if code, body = get("/livez"); code != http.StatusOK || body["status"] != "up" {
+		t.Errorf("外部依赖故障不应影响存活检查: %d %v", code, body)
+	}
+
+	resp, _ := http.Post(server.URL+"/readyz", "text/plain", nil)
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST 应返回 405: %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,234 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Status 检查状态
+type Status string
+
+const (
+	StatusUp       Status = "up"       // 全部通过
+	StatusDegraded Status = "degraded" // 仅非关键检查失败，仍可对外服务
+	StatusDown     Status = "down"     // 关键检查失败
+)
+
+// ErrTimeout 检查超时
+var ErrTimeout = errors.New("health: check timeout")
+
+// CheckFunc 检查函数，返回 nil 表示健康。应遵守 ctx 的超时
+type CheckFunc func(ctx context.Context) error
+
+// Check 注册的检查项
+type Check struct {
+	Name     string        // 名称，唯一
+	Check    CheckFunc     // 检查函数
+	Timeout  time.Duration // 单次检查超时，默认 Config.Timeout
+	Critical bool          // 关键检查失败时整体为 down，非关键失败为 degraded
+	Liveness bool          // 同时参与存活检查；默认只参与就绪检查，外部依赖故障不应导致容器被重启
+}
+
+// Config 健康检查配置
+type Config struct {
+	Timeout  time.Duration // 默认单次检查超时，默认 3 秒
+	CacheTTL time.Duration // 检查结果缓存时间，默认 2 秒；负数表示不缓存
+	Logger   *zap.Logger   // 状态变化日志，为 nil 时不输出
+}
+
+// Result 单项检查结果
+type Result struct {
+	Name      string        `json:"name"`
+	Status    Status        `json:"status"`
+	Critical  bool          `json:"critical"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration"` // JSON 中输出为字符串，如 "1.2ms"
+	CheckedAt time.Time     `json:"checked_at"`
+}
+
+// MarshalJSON 耗时以可读字符串输出
+func (r Result) MarshalJSON() ([]byte, error) {
+	type plain Result
+	return json.Marshal(struct {
+		plain
+		Duration string `json:"duration"`
+	}{plain(r), r.Duration.String()})
+}
+
+// Report 汇总报告
+type Report struct {
+	Status    Status    `json:"status"`
+	Checks    []Result  `json:"checks"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// entry 检查项及其缓存结果。mutex 在检查期间保持，并发请求只触发一次检查
+type entry struct {
+	check Check
+
+	mutex   sync.Mutex
+	result  Result
+	expires time.Time
+	checked bool
+}
+
+// Health 健康检查聚合器，组件通过 Register 注册检查项
+type Health struct {
+	config Config
+
+	mutex   sync.RWMutex
+	entries []*entry
+	names   map[string]bool
+}
+
+// New 创建健康检查聚合器
+func New(config Config) *Health {
+	if config.Timeout <= 0 {
+		config.Timeout = 3 * time.Second
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = 2 * time.Second
+	}
+	return &Health{config: config, names: make(map[string]bool)}
+}
+
+// Register 注册检查项，名称重复时返回错误
+func (h *Health) Register(check Check) error {
+	if check.Name == "" || check.Check == nil {
+		return errors.New("health: check name and func are required")
+	}
+	if check.Timeout <= 0 {
+		check.Timeout = h.config.Timeout
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.names[check.Name] {
+		return fmt.Errorf("health: check %q already registered", check.Name)
+	}
+	h.names[check.Name] = true
+	h.entries = append(h.entries, &entry{check: check})
+	return nil
+}
+
+// Live 存活检查，只执行 Liveness 为 true 的检查项；无检查项时为 up
+func (h *Health) Live(ctx context.Context) Report {
+	return h.run(ctx, true)
+}
+
+// Ready 就绪检查，执行全部检查项
+func (h *Health) Ready(ctx context.Context) Report {
+	return h.run(ctx, false)
+}
+
+func (h *Health) run(ctx context.Context, liveness bool) Report {
+	h.mutex.RLock()
+	entries := make([]*entry, 0, len(h.entries))
+	for _, e := range h.entries {
+		if !liveness || e.check.Liveness {
+			entries = append(entries, e)
+		}
+	}
+	h.mutex.RUnlock()
+
+	report := Report{Status: StatusUp, Checks: make([]Result, len(entries))}
+	var wg sync.WaitGroup
+	for i, e := range entries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = h.result(ctx, e)
+		}()
+	}
+	wg.Wait()
+
+	for _, r := range report.Checks {
+		if r.Status == StatusUp {
+			continue
+		}
+		if r.Critical {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+	report.Timestamp = time.Now()
+	return report
+}
+
+// result 返回缓存结果，过期时重新检查
+func (h *Health) result(ctx context.Context, e *entry) Result {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	now := time.Now()
+	if e.checked && now.Before(e.expires) {
+		return e.result
+	}
+
+	err := execute(ctx, e.check)
+	result := Result{
+		Name:      e.check.Name,
+		Status:    StatusUp,
+		Critical:  e.check.Critical,
+		Duration:  time.Since(now),
+		CheckedAt: now,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	h.logChange(e, result)
+
+	// 调用方取消（如客户端断开）导致的失败不缓存
+	if ctx.Err() == nil {
+		e.result, e.checked = result, true
+		e.expires = now.Add(h.config.CacheTTL)
+	}
+	return result
+}
+
+// execute 在超时内执行检查；检查函数未响应 ctx 时不等待其返回，panic 视为失败
+func execute(ctx context.Context, check Check) error {
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("health: check panic: %v", r)
+			}
+		}()
+		done <- check.Check(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ErrTimeout
+		}
+		return ctx.Err()
+	}
+}
+
+// logChange 状态变化时输出日志
+func (h *Health) logChange(e *entry, result Result) {
+	if h.config.Logger == nil {
+		return
+	}
+	previous := StatusUp
+	if e.checked {
+		previous = e.result.Status
+	}
+	switch {
+	case result.Status != StatusUp && previous == StatusUp:
+		h.config.Logger.Warn("[Health] 检查失败", zap.String("check", result.Name), zap.Bool("critical", result.Critical), zap.String("error", result.Error))
+	case result.Status == StatusUp && previous != StatusUp:
+		h.config.Logger.Info("[Health] 检查恢复", zap.String("check", result.Name))
+	}
+}
@@ -0,0 +1,120 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type fakeServer struct{ running atomic.Bool }
+
+func (s *fakeServer) IsRunning() bool { return s.running.Load() }
+
+type fakePinger struct{ err error }
+
+func (p fakePinger) Ping() error { return p.err }
+
+func TestHealth_Status(t *testing.T) {
+	h := New(Config{CacheTTL: -1})
+	server := &fakeServer{}
+	server.running.Store(true)
+	h.Register(Check{Name: "websocket", Check: RunningCheck(server), Critical: true, Liveness: true})
+	h.Register(Check{Name: "redis", Check: PingCheck(fakePinger{})})
+	if err := h.Register(Check{Name: "redis", Check: PingCheck(fakePinger{})}); err == nil {
+		t.Error("重复名称应返回错误")
+	}
+
+	ctx := context.Background()
+	if r := h.Ready(ctx); r.Status != StatusUp || len(r.Checks) != 2 {
+		t.Fatalf("全部通过时应为 up: %+v", r)
+	}
+
+	h.Register(Check{Name: "cache", Check: PingCheck(fakePinger{err: errors.New("refused")})})
+	r := h.Ready(ctx)
+	if r.Status != StatusDegraded {
+		t.Errorf("非关键检查失败应为 degraded: %s", r.Status)
+	}
+	if r.Checks[2].Name != "cache" || r.Checks[2].Error != "refused" {
+		t.Errorf("结果应按注册顺序并包含错误: %+v", r.Checks)
+	}
+	if live := h.Live(ctx); live.Status != StatusUp || len(live.Checks) != 1 {
+		t.Errorf("存活检查只包含 Liveness 检查项: %+v", live)
+	}
+
+	server.running.Store(false)
+	if r := h.Ready(ctx); r.Status != StatusDown {
+		t.Errorf("关键检查失败应为 down: %s", r.Status)
+	}
+}
+
+func TestHealth_TimeoutAndPanic(t *testing.T) {
+	h := New(Config{Timeout: 20 * time.Millisecond})
+	h.Register(Check{Name: "slow", Critical: true, Check: func(ctx context.Context) error {
+		time.Sleep(time.Second) // 不响应 ctx
+		return nil
+	}})
+	h.Register(Check{Name: "panic", Check: func(context.Context) error { panic("boom") }})
+
+	start := time.Now()
+	r := h.Ready(context.Background())
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("超时检查不应阻塞: %v", time.Since(start))
+	}
+	if r.Status != StatusDown || r.Checks[0].Error != ErrTimeout.Error() {
+		t.Errorf("超时应视为失败: %+v", r.Checks[0])
+	}
+	if r.Checks[1].Status != StatusDown {
+		t.Errorf("panic 应视为失败: %+v", r.Checks[1])
+	}
+}
+
+func TestHealth_Cache(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	h := New(Config{CacheTTL: time.Hour, Logger: zap.New(core)})
+	var calls atomic.Int32
+	var fail atomic.Bool
+	fail.Store(true)
+	h.Register(Check{Name: "db", Critical: true, Check: func(context.Context) error {
+		calls.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		if fail.Load() {
+			return errors.New("down")
+		}
+		return nil
+	}})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.Ready(context.Background())
+		}()
+	}
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("缓存期内并发请求只应检查一次: %d", calls.Load())
+	}
+	if logs.FilterMessage("[Health] 检查失败").Len() != 1 {
+		t.Error("状态变为失败时应输出日志")
+	}
+
+	// 调用方取消的检查结果不缓存
+	h2 := New(Config{CacheTTL: time.Hour})
+	h2.Register(Check{Name: "db", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	h2.Ready(ctx)
+	if h2.entries[0].checked {
+		t.Error("调用方取消的结果不应缓存")
+	}
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"go.uber.org/zap"
+)
+
+type (
+	ctxKeyTraceID   struct{}
+	ctxKeyRequestID struct{}
+)
+
+// WithTraceID 在 context 中写入链路 ID，下游（如 SQL 日志）通过 TraceID 读取
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, ctxKeyTraceID{}, traceID)
+}
+
+// TraceID 读取 context 中的链路 ID，未设置时返回空串
+func TraceID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(ctxKeyTraceID{}).(string)
+	return id
+}
+
+// WithRequestID 在 context 中写入请求 ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, ctxKeyRequestID{}, requestID)
+}
+
+// RequestID 读取 context 中的请求 ID，未设置时返回空串
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(ctxKeyRequestID{}).(string)
+	return id
+}
+
+// NewTraceID 生成 32 位十六进制链路 ID（与 W3C traceparent 的 trace-id 格式一致）
+func NewTraceID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// ContextFields 返回 context 中的 trace_id / request_id 字段，未设置的字段不输出
+func ContextFields(ctx context.Context) []zap.Field {
+	var fields []zap.Field
+	if id := TraceID(ctx); id != "" {
+		fields = append(fields, zap.String("trace_id", id))
+	}
+	if id := RequestID(ctx); id != "" {
+		fields = append(fields, zap.String("request_id", id))
+	}
+	return fields
+}
+
+// WithContext 返回附带 context 中 trace_id / request_id 的全局 Logger，未初始化时返回 Nop Logger
+func WithContext(ctx context.Context) *zap.Logger {
+	if Logger == nil {
+		return zap.NewNop()
+	}
+	return Logger.With(ContextFields(ctx)...)
+}
@@ -0,0 +1,26 @@
+package logger
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContextTraceID(t *testing.T) {
+	ctx := context.Background()
+	if TraceID(ctx) != "" || len(ContextFields(ctx)) != 0 {
+		t.Error("未设置时应为空")
+	}
+	ctx = WithRequestID(WithTraceID(ctx, "trace-1"), "req-1")
+	if TraceID(ctx) != "trace-1" || RequestID(ctx) != "req-1" {
+		t.Errorf("读取失败: %q %q", TraceID(ctx), RequestID(ctx))
+	}
+	if fields := ContextFields(ctx); len(fields) != 2 || fields[0].Key != "trace_id" || fields[1].Key != "request_id" {
+		t.Errorf("字段不符: %+v", fields)
+	}
+	if id := NewTraceID(); len(id) != 32 || id == NewTraceID() {
+		t.Errorf("链路 ID 格式不符: %q", id)
+	}
+	if WithContext(ctx) == nil {
+		t.Error("WithContext 不应返回 nil")
+	}
+}
@@ -0,0 +1,33 @@
+package mq
+
+// Publisher 消息发布接口，RabbitMQClient 与 MemoryBus 均实现该接口
+type Publisher interface {
+	// Publish 发布到默认 exchange 下的指定队列
+	Publish(queueName string, body []byte) error
+	// PublishWithExchange 发布原始消息体
+	PublishWithExchange(exchange, exchangeType, routingKey string, body []byte) error
+	// PublishMessage 发布消息信封
+	PublishMessage(exchange, exchangeType, routingKey string, msg *Message) error
+}
+
+// Subscriber 消息订阅接口，RabbitMQClient 与 MemoryBus 均实现该接口
+type Subscriber interface {
+	// Consume 从默认 exchange 消费指定队列
+	Consume(queueName string, concurrency int, handler func(msg string)) error
+	// ConsumeWithExchange 以字符串形式消费
+	ConsumeWithExchange(exchange, exchangeType, queueName, routingKey string, concurrency int, handler func(msg string)) error
+	// ConsumeMessage 以消息信封形式消费
+	ConsumeMessage(exchange, exchangeType, queueName, routingKey string, concurrency int, handler MessageHandler) error
+}
+
+// Bus 消息总线，业务代码依赖该接口即可在 RabbitMQ 与内存实现之间切换
+type Bus interface {
+	Publisher
+	Subscriber
+	Close() error
+}
+
+var (
+	_ Bus = (*RabbitMQClient)(nil)
+	_ Bus = (*MemoryBus)(nil)
+)
@@ -0,0 +1,37 @@
+package mq
+
+import "context"
+
+// ConfirmPublisher 支持发布确认的发布接口，用于需要确保消息已被服务端接收的场景（如事务发件箱）
+type ConfirmPublisher interface {
+	PublishMessageWithConfirm(ctx context.Context, exchange, exchangeType, routingKey string, msg *Message) error
+}
+
+var (
+	_ ConfirmPublisher = (*RabbitMQClient)(nil)
+	_ ConfirmPublisher = (*MemoryBus)(nil)
+)
+
+// PublishMessageWithConfirm 以 publisher confirm 模式发布消息，服务端确认后才返回
+// 返回 nil 表示消息已被服务端持久接收（持久化消息需投递到持久化队列）。
+// 以 mandatory 方式发布，没有匹配队列的消息会被退回并返回 ErrUnroutable，而不是确认后静默丢弃
+func (c *RabbitMQClient) PublishMessageWithConfirm(ctx context.Context, exchange, exchangeType, routingKey string, msg *Message) error {
+	if err := msg.fillDefaults(); err != nil {
+		return err
+	}
+	return c.publishConfirmed(ctx, exchange, exchangeType, routingKey, msg.toPublishing())
+}
+
+// PublishMessageWithConfirm 内存实现，入队即视为确认；没有匹配队列时返回 ErrUnroutable
+func (b *MemoryBus) PublishMessageWithConfirm(ctx context.Context, exchange, exchangeType, routingKey string, msg *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mutex.Lock()
+	routes := b.routeLocked(exchange, routingKey)
+	b.mutex.Unlock()
+	if len(routes) == 0 {
+		return ErrUnroutable
+	}
+	return b.PublishMessage(exchange, exchangeType, routingKey, msg)
+}
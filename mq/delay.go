@@ -0,0 +1,223 @@
+package mq
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// 延迟投递使用的消息头
+// headers exchange 匹配时会忽略 x- 开头的消息头，因此这里不使用 x- 前缀
+const (
+	HeaderDelayTier     = "mq-delay-tier"
+	HeaderDelayExchange = "mq-delay-exchange"
+	HeaderDelayQueue    = "mq-delay-queue"
+)
+
+// ErrDelayTooLong 延迟超过最大分级
+var ErrDelayTooLong = errors.New("mq delay: delay exceeds the largest tier")
+
+// DelayConfig 延迟投递配置
+//
+// 实现原理（无需 rabbitmq_delayed_message_exchange 插件）：
+//   - 消息发布到 headers 类型的入口 exchange，按延迟时长路由到对应的分级队列
+//   - 分级队列设置队列级 TTL（x-message-ttl）且没有消费者，消息到期后死信到 headers 类型的分发 exchange，路由键保持不变
+//   - 分发 exchange 按目标 exchange/队列消息头路由到业务 exchange 或队列，消费端无感知
+//
+// RabbitMQ 只在队头检查过期，同一队列内的消息 TTL 必须相同才不会互相阻塞，
+// 因此延迟向上取整到不小于它的最小分级，实际延迟即该分级时长；超过最大分级的延迟返回 ErrDelayTooLong。
+type DelayConfig struct {
+	Exchange         string          // 入口 exchange，默认 mq.delay
+	DispatchExchange string          // 分发 exchange，默认 mq.delay.dispatch
+	QueuePrefix      string          // 分级队列名前缀，默认 mq.delay.
+	Tiers            []time.Duration // 延迟分级，默认 1s/5s/10s/30s/1m/5m/10m/30m/1h/2h/6h/12h/24h
+}
+
+// delayState 延迟投递运行状态
+type delayState struct {
+	config  DelayConfig
+	targets map[string]struct{} // 已绑定到分发 exchange 的目标
+}
+
+// withDefaults 填充默认值并对分级排序
+func (cfg DelayConfig) withDefaults() DelayConfig {
+	if cfg.Exchange == "" {
+		cfg.Exchange = "mq.delay"
+	}
+	if cfg.DispatchExchange == "" {
+		cfg.DispatchExchange = "mq.delay.dispatch"
+	}
+	if cfg.QueuePrefix == "" {
+		cfg.QueuePrefix = "mq.delay."
+	}
+	if len(cfg.Tiers) == 0 {
+		cfg.Tiers = []time.Duration{
+			time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+			time.Minute, 5 * time.Minute, 10 * time.Minute, 30 * time.Minute,
+			time.Hour, 2 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+		}
+	}
+	tiers := make([]time.Duration, len(cfg.Tiers))
+	copy(tiers, cfg.Tiers)
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i] < tiers[j] })
+	cfg.Tiers = tiers
+	return cfg
+}
+
+// tierName 分级名称，如 10s、1m0s
+func tierName(d time.Duration) string {
+	return d.String()
+}
+
+// tierFor 选择不小于 delay 的最小分级，超出最大分级时返回 ErrDelayTooLong
+func (cfg DelayConfig) tierFor(delay time.Duration) (time.Duration, error) {
+	for _, t := range cfg.Tiers {
+		if delay <= t {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %v > %v", ErrDelayTooLong, delay, cfg.Tiers[len(cfg.Tiers)-1])
+}
+
+// topology 延迟投递所需的 exchange、分级队列及绑定
+func (cfg DelayConfig) topology() Topology {
+	topo := Topology{
+		Exchanges: []ExchangeConfig{
+			{Name: cfg.Exchange, Type: amqp.ExchangeHeaders, Durable: true},
+			{Name: cfg.DispatchExchange, Type: amqp.ExchangeHeaders, Durable: true},
+		},
+	}
+	for _, t := range cfg.Tiers {
+		name := cfg.QueuePrefix + tierName(t)
+		topo.Queues = append(topo.Queues, QueueConfig{
+			Name:               name,
+			Durable:            true,
+			MessageTTL:         t,
+			DeadLetterExchange: cfg.DispatchExchange,
+		})
+		topo.Bindings = append(topo.Bindings, BindingConfig{
+			Exchange: cfg.Exchange,
+			Queue:    name,
+			Args:     map[string]any{"x-match": "all", HeaderDelayTier: tierName(t)},
+		})
+	}
+	return topo
+}
+
+// targetBinding 分发 exchange 到目标 exchange（或默认 exchange 下的队列）的绑定
+func (cfg DelayConfig) targetBinding(exchange, routingKey string) BindingConfig {
+	if exchange == "" {
+		return BindingConfig{
+			Exchange: cfg.DispatchExchange,
+			Queue:    routingKey,
+			Args:     map[string]any{"x-match": "all", HeaderDelayQueue: routingKey},
+		}
+	}
+	return BindingConfig{
+		Exchange:   cfg.DispatchExchange,
+		ToExchange: exchange,
+		Args:       map[string]any{"x-match": "all", HeaderDelayExchange: exchange},
+	}
+}
+
+// EnableDelayedDelivery 声明延迟投递所需拓扑，未调用时 PublishDelayed 会使用默认配置自动启用
+func (c *RabbitMQClient) EnableDelayedDelivery(cfg DelayConfig) error {
+	cfg = cfg.withDefaults()
+	for _, t := range cfg.Tiers {
+		if t <= 0 {
+			return errors.New("mq delay: tier must be positive")
+		}
+	}
+	if err := c.DeclareTopology(cfg.topology()); err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	c.delay = &delayState{config: cfg, targets: make(map[string]struct{})}
+	c.mutex.Unlock()
+	return nil
+}
+
+// delayConfigFor 获取延迟配置，并确保目标已绑定到分发 exchange
+func (c *RabbitMQClient) delayConfigFor(exchange, routingKey string) (DelayConfig, error) {
+	c.mutex.Lock()
+	state := c.delay
+	c.mutex.Unlock()
+	if state == nil {
+		if err := c.EnableDelayedDelivery(DelayConfig{}); err != nil {
+			return DelayConfig{}, err
+		}
+		c.mutex.Lock()
+		state = c.delay
+		c.mutex.Unlock()
+	}
+
+	target := exchange
+	if exchange == "" {
+		target = "queue:" + routingKey
+	}
+	c.mutex.Lock()
+	_, bound := state.targets[target]
+	c.mutex.Unlock()
+	if !bound {
+		binding := state.config.targetBinding(exchange, routingKey)
+		if err := c.DeclareTopology(Topology{Bindings: []BindingConfig{binding}}); err != nil {
+			return DelayConfig{}, err
+		}
+		c.mutex.Lock()
+		state.targets[target] = struct{}{}
+		c.mutex.Unlock()
+	}
+	return state.config, nil
+}
+
+// PublishDelayed 延迟 delay 后将 body 投递到 exchange/routingKey，消息默认持久化
+// exchange 为空时 routingKey 表示目标队列名；目标 exchange/队列需事先存在
+func (c *RabbitMQClient) PublishDelayed(exchange, routingKey string, body []byte, delay time.Duration) error {
+	msg, err := NewMessage(body, WithPersistent())
+	if err != nil {
+		return err
+	}
+	return c.PublishDelayedMessage(exchange, routingKey, msg, delay)
+}
+
+// PublishDelayedMessage 延迟投递消息信封，到期后消费端按正常消息收到。
+// 实际延迟为不小于 delay 的最小分级；消息自身的 Expiration 会被清除，以免与分级队列 TTL 冲突
+func (c *RabbitMQClient) PublishDelayedMessage(exchange, routingKey string, msg *Message, delay time.Duration) error {
+	cfg, err := c.delayConfigFor(exchange, routingKey)
+	if err != nil {
+		return err
+	}
+	pub, err := cfg.publishing(exchange, routingKey, msg, delay)
+	if err != nil {
+		return err
+	}
+	return c.publish(cfg.Exchange, amqp.ExchangeHeaders, routingKey, pub)
+}
+
+// publishing 构造发往入口 exchange 的延迟消息
+func (cfg DelayConfig) publishing(exchange, routingKey string, msg *Message, delay time.Duration) (amqp.Publishing, error) {
+	tier, err := cfg.tierFor(max(delay, 0))
+	if err != nil {
+		return amqp.Publishing{}, err
+	}
+
+	// 复制消息，避免修改调用方的消息头
+	m := *msg
+	m.Headers = maps.Clone(msg.Headers)
+	m.SetHeader(HeaderDelayTier, tierName(tier))
+	if exchange == "" {
+		m.SetHeader(HeaderDelayQueue, routingKey)
+	} else {
+		m.SetHeader(HeaderDelayExchange, exchange)
+	}
+	if err := m.fillDefaults(); err != nil {
+		return amqp.Publishing{}, err
+	}
+	pub := m.toPublishing()
+	pub.Expiration = ""
+	return pub, nil
+}
@@ -0,0 +1,84 @@
+package mq
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDelayConfig_TierFor(t *testing.T) {
+	cfg := DelayConfig{Tiers: []time.Duration{time.Minute, time.Second, 10 * time.Second}}.withDefaults()
+	cases := []struct {
+		delay time.Duration
+		want  time.Duration
+	}{
+		{0, time.Second},
+		{time.Second, time.Second},
+		{1500 * time.Millisecond, 10 * time.Second},
+		{30 * time.Second, time.Minute},
+	}
+	for _, c := range cases {
+		if got, err := cfg.tierFor(c.delay); err != nil || got != c.want {
+			t.Errorf("tierFor(%v)=%v, %v want=%v", c.delay, got, err, c.want)
+		}
+	}
+	if _, err := cfg.tierFor(time.Hour); !errors.Is(err, ErrDelayTooLong) {
+		t.Errorf("超过最大分级应返回 ErrDelayTooLong: %v", err)
+	}
+}
+
+func TestDelayConfig_MixedDelaysInTier(t *testing.T) {
+	cfg := DelayConfig{Tiers: []time.Duration{10 * time.Second, 5 * time.Minute}}.withDefaults()
+	msg, _ := NewMessage([]byte("x"), WithExpiration(time.Hour))
+
+	// 5m 分级内的 31s 与 5m 消息进入同一队列，依赖队列级 TTL 而非单条 TTL，后入队的消息不会被前面的消息阻塞
+	for _, delay := range []time.Duration{5 * time.Minute, 31 * time.Second} {
+		pub, err := cfg.publishing("order", "order.timeout", msg, delay)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pub.Headers[HeaderDelayTier] != "5m0s" {
+			t.Errorf("%v 应进入 5m 分级: %v", delay, pub.Headers[HeaderDelayTier])
+		}
+		if pub.Expiration != "" {
+			t.Errorf("延迟消息不应携带单条 TTL: %q", pub.Expiration)
+		}
+	}
+	for _, q := range cfg.topology().Queues {
+		if q.Name == "mq.delay.5m0s" && q.arguments()["x-message-ttl"] != int64(5*time.Minute/time.Millisecond) {
+			t.Errorf("分级队列应设置队列级 TTL: %v", q.arguments())
+		}
+	}
+	if _, err := cfg.publishing("order", "order.timeout", msg, 6*time.Minute); !errors.Is(err, ErrDelayTooLong) {
+		t.Errorf("超过最大分级应返回 ErrDelayTooLong: %v", err)
+	}
+	if msg.Expiration != time.Hour {
+		t.Error("不应修改调用方的消息")
+	}
+}
+
+func TestDelayConfig_Topology(t *testing.T) {
+	cfg := DelayConfig{Tiers: []time.Duration{time.Second, 10 * time.Second}}.withDefaults()
+	topo := cfg.topology()
+	if err := topo.Validate(); err != nil {
+		t.Fatalf("拓扑校验失败: %v", err)
+	}
+	if len(topo.Queues) != 2 || topo.Queues[1].Name != "mq.delay.10s" {
+		t.Fatalf("分级队列不符: %+v", topo.Queues)
+	}
+	if args := topo.Queues[0].arguments(); args["x-dead-letter-exchange"] != "mq.delay.dispatch" {
+		t.Errorf("分级队列死信配置不符: %v", args)
+	}
+	if topo.Bindings[1].Args[HeaderDelayTier] != "10s" {
+		t.Errorf("分级绑定不符: %+v", topo.Bindings[1])
+	}
+
+	b := cfg.targetBinding("order", "order.timeout")
+	if b.ToExchange != "order" || b.Args[HeaderDelayExchange] != "order" {
+		t.Errorf("exchange 目标绑定不符: %+v", b)
+	}
+	b = cfg.targetBinding("", "settle.retry")
+	if b.Queue != "settle.retry" || b.Args[HeaderDelayQueue] != "settle.retry" {
+		t.Errorf("队列目标绑定不符: %+v", b)
+	}
+}
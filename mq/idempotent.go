@@ -0,0 +1,182 @@
+package mq
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/muchinfo/mtp2-common-lib/redis"
+	"github.com/muchinfo/mtp2-common-lib/ulidgen"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ErrInProgress 相同消息正由其他消费者处理中，消息不应被确认，需稍后重投（如配合 ConsumeWithRetry）
+var ErrInProgress = errors.New("mq: message is being processed")
+
+// IdempotencyState 去重记录状态
+type IdempotencyState int
+
+const (
+	IdempotencyAcquired   IdempotencyState = iota // 首次处理，已写入处理中标记
+	IdempotencyProcessing                         // 其他消费者正在处理
+	IdempotencyDone                               // 已处理完成
+)
+
+// IdempotencyStore 幂等记录存储
+type IdempotencyStore interface {
+	// Acquire 不存在时以 ttl 写入持有者为 token 的处理中标记并返回 IdempotencyAcquired，已存在时返回当前状态
+	Acquire(key, token string, ttl time.Duration) (IdempotencyState, error)
+	// Complete 将记录标记为已完成并以 ttl 保留
+	Complete(key string, ttl time.Duration) error
+	// Release 仅当 key 仍是 token 写入的处理中标记时删除，使消息可被再次处理；
+	// 标记已到期并被其他消费者重新获取时不删除
+	Release(key, token string) error
+}
+
+// 去重记录的值，处理中标记为 processing:<token>
+const (
+	idemProcessing = "processing:"
+	idemDone       = "done"
+)
+
+// RedisIdempotencyStore 基于 Redis SETNX + TTL 的幂等记录存储
+type RedisIdempotencyStore struct {
+	client *redis.RedisClient
+}
+
+// NewRedisIdempotencyStore 创建 Redis 幂等记录存储
+func NewRedisIdempotencyStore(client *redis.RedisClient) *RedisIdempotencyStore {
+	return &RedisIdempotencyStore{client: client}
+}
+
+// Acquire 使用 SETNX 写入处理中标记，已存在时读取其状态
+func (s *RedisIdempotencyStore) Acquire(key, token string, ttl time.Duration) (IdempotencyState, error) {
+	ok, err := s.client.SetNX(key, idemProcessing+token, ttl)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		return IdempotencyAcquired, nil
+	}
+	value, err := s.client.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	// 标记恰好在两次调用之间过期时按处理中对待，稍后重投即可
+	if value == idemDone {
+		return IdempotencyDone, nil
+	}
+	return IdempotencyProcessing, nil
+}
+
+// Complete 标记为已完成
+func (s *RedisIdempotencyStore) Complete(key string, ttl time.Duration) error {
+	return s.client.Set(key, idemDone, ttl)
+}
+
+// Release 以 WATCH 事务比较后删除，key 已不是 token 的处理中标记时忽略
+func (s *RedisIdempotencyStore) Release(key, token string) error {
+	ctx := s.client.GetContext()
+	err := s.client.Watch(func(tx *goredis.Tx) error {
+		value, err := tx.Get(ctx, key).Result()
+		if err == goredis.Nil || (err == nil && value != idemProcessing+token) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+			pipe.Del(ctx, key)
+			return nil
+		})
+		return err
+	}, key)
+	// 比较后 key 被其他消费者修改，说明已不属于本次处理
+	if errors.Is(err, goredis.TxFailedErr) {
+		return nil
+	}
+	return err
+}
+
+// IdempotencyConfig 幂等消费配置
+type IdempotencyConfig struct {
+	Prefix        string                // key 前缀，默认 mq:idem:
+	TTL           time.Duration         // 处理完成后去重记录保留时间，默认 24 小时
+	ProcessingTTL time.Duration         // 处理中标记有效期，默认 5 分钟，应大于 handler 最长处理时间；进程崩溃后标记到期，重投的消息可再次处理
+	KeyFunc       func(*Message) string // 提取去重 ID，默认使用消息 ID；返回空字符串时不去重
+	FailOpen      bool                  // 存储不可用时是否仍处理消息，默认 false（返回错误，由重试机制处理）
+	Logger        *zap.Logger           // 日志，允许为 nil
+}
+
+// Idempotent 幂等消费中间件：处理前以短 TTL 写入处理中标记，handler 成功后改为已完成并以 TTL 保留，
+// 失败时删除标记以便重试时再次处理。已完成的重复消息直接确认跳过；
+// 仍在处理中的重复消息返回 ErrInProgress 而不确认，须配合 ConsumeWithRetry 延迟重投：
+// ConsumeMessage 对失败消息 Nack 且不重新入队，首次处理随后失败时该消息将丢失
+//
+//	client.ConsumeWithRetry(exchange, typ, queue, key, 2, mq.RetryPolicy{}, mq.Idempotent(store, mq.IdempotencyConfig{})(handler))
+func Idempotent(store IdempotencyStore, config IdempotencyConfig) func(MessageHandler) MessageHandler {
+	if config.Prefix == "" {
+		config.Prefix = "mq:idem:"
+	}
+	if config.TTL <= 0 {
+		config.TTL = 24 * time.Hour
+	}
+	if config.ProcessingTTL <= 0 {
+		config.ProcessingTTL = 5 * time.Minute
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = func(m *Message) string { return m.ID }
+	}
+	return func(next MessageHandler) MessageHandler {
+		return func(msg *Message) error {
+			id := config.KeyFunc(msg)
+			if id == "" {
+				if config.Logger != nil {
+					config.Logger.Warn("[RabbitMQ] 消息缺少去重 ID，跳过幂等检查", zap.String("routing_key", msg.RoutingKey))
+				}
+				return next(msg)
+			}
+			key := config.Prefix + id
+
+			token, err := ulidgen.GenerateULID()
+			if err != nil {
+				return err
+			}
+			state, err := store.Acquire(key, token, config.ProcessingTTL)
+			if err != nil {
+				if !config.FailOpen {
+					return fmt.Errorf("idempotency acquire %s: %w", key, err)
+				}
+				if config.Logger != nil {
+					config.Logger.Warn("[RabbitMQ] 幂等存储不可用，继续处理消息", zap.String("id", id), zap.Error(err))
+				}
+				return next(msg)
+			}
+			switch state {
+			case IdempotencyDone:
+				if config.Logger != nil {
+					config.Logger.Info("[RabbitMQ] 重复消息，已跳过", zap.String("id", id))
+				}
+				return nil
+			case IdempotencyProcessing:
+				if config.Logger != nil {
+					config.Logger.Info("[RabbitMQ] 重复消息正在处理中，稍后重试", zap.String("id", id))
+				}
+				return fmt.Errorf("%w: %s", ErrInProgress, id)
+			}
+
+			if herr := next(msg); herr != nil {
+				if err := store.Release(key, token); err != nil && config.Logger != nil {
+					config.Logger.Error("[RabbitMQ] 释放幂等记录失败", zap.String("id", id), zap.Error(err))
+				}
+				return herr
+			}
+			// 标记失败时处理中标记到期后重投的消息会再次处理（至少一次），不影响本次确认
+			if err := store.Complete(key, config.TTL); err != nil && config.Logger != nil {
+				config.Logger.Error("[RabbitMQ] 更新幂等记录失败", zap.String("id", id), zap.Error(err))
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package mq
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/muchinfo/mtp2-common-lib/redis"
+)
+
+// memoryIdempotencyStore 测试用内存存储
+type memoryIdempotencyStore struct {
+	mutex sync.Mutex
+	keys  map[string]memoryIdempotencyEntry
+	err   error
+}
+
+type memoryIdempotencyEntry struct {
+	value   string
+	expires time.Time
+}
+
+func (s *memoryIdempotencyStore) Acquire(key, token string, ttl time.Duration) (IdempotencyState, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.err != nil {
+		return 0, s.err
+	}
+	if e, ok := s.keys[key]; ok && time.Now().Before(e.expires) {
+		if e.value == idemDone {
+			return IdempotencyDone, nil
+		}
+		return IdempotencyProcessing, nil
+	}
+	s.keys[key] = memoryIdempotencyEntry{value: idemProcessing + token, expires: time.Now().Add(ttl)}
+	return IdempotencyAcquired, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(key string, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keys[key] = memoryIdempotencyEntry{value: idemDone, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(key, token string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.keys[key].value == idemProcessing+token {
+		delete(s.keys, key)
+	}
+	return nil
+}
+
+func TestIdempotent_SkipDuplicatesAndReleaseOnFailure(t *testing.T) {
+	store := &memoryIdempotencyStore{keys: make(map[string]memoryIdempotencyEntry)}
+	calls := 0
+	fail := true
+	handler := Idempotent(store, IdempotencyConfig{})(func(msg *Message) error {
+		calls++
+		if fail {
+			return errors.New("处理失败")
+		}
+		return nil
+	})
+
+	msg := &Message{ID: "01HZX"}
+	if err := handler(msg); err == nil {
+		t.Fatal("handler 失败时应返回错误")
+	}
+	if _, ok := store.keys["mq:idem:01HZX"]; ok {
+		t.Fatal("handler 失败后应释放去重记录")
+	}
+
+	fail = false
+	if err := handler(msg); err != nil {
+		t.Fatalf("重试处理失败: %v", err)
+	}
+	if err := handler(msg); err != nil {
+		t.Fatalf("重复消息应直接确认: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("handler 调用次数=%d want=2", calls)
+	}
+}
+
+func TestIdempotent_StoreUnavailable(t *testing.T) {
+	store := &memoryIdempotencyStore{keys: make(map[string]memoryIdempotencyEntry), err: errors.New("redis down")}
+	next := func(msg *Message) error { return nil }
+
+	if err := Idempotent(store, IdempotencyConfig{})(next)(&Message{ID: "1"}); err == nil {
+		t.Error("默认配置下存储不可用应返回错误")
+	}
+	if err := Idempotent(store, IdempotencyConfig{FailOpen: true})(next)(&Message{ID: "1"}); err != nil {
+		t.Errorf("FailOpen 时应继续处理: %v", err)
+	}
+}
+
+func TestRedisIdempotencyStore(t *testing.T) {
+	client, err := redis.NewRedisClient(redis.RedisConfig{Address: "localhost:6379", Database: 1})
+	if err != nil {
+		t.Skipf("Redis server not available: %v", err)
+		return
+	}
+	defer client.Close()
+
+	store := NewRedisIdempotencyStore(client)
+	key := "test:mq:idem"
+	client.Del(key)
+	defer client.Del(key)
+
+	state, err := store.Acquire(key, "a", time.Minute)
+	if err != nil || state != IdempotencyAcquired {
+		t.Fatalf("首次 Acquire 失败: state=%v err=%v", state, err)
+	}
+	state, err = store.Acquire(key, "b", time.Minute)
+	if err != nil || state != IdempotencyProcessing {
+		t.Fatalf("处理中重复 Acquire 应返回 IdempotencyProcessing: state=%v err=%v", state, err)
+	}
+	if err := store.Release(key, "b"); err != nil {
+		t.Fatalf("Release 失败: %v", err)
+	}
+	if n, _ := client.Exists(key); n != 1 {
+		t.Fatal("非持有者不应删除处理中标记")
+	}
+	if err := store.Complete(key, time.Minute); err != nil {
+		t.Fatalf("Complete 失败: %v", err)
+	}
+	state, err = store.Acquire(key, "c", time.Minute)
+	if err != nil || state != IdempotencyDone {
+		t.Fatalf("完成后重复 Acquire 应返回 IdempotencyDone: state=%v err=%v", state, err)
+	}
+}
+
+func TestIdempotent_InProgressAndCrash(t *testing.T) {
+	store := &memoryIdempotencyStore{keys: make(map[string]memoryIdempotencyEntry)}
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := Idempotent(store, IdempotencyConfig{ProcessingTTL: 50 * time.Millisecond})(func(msg *Message) error {
+		close(started)
+		<-release
+		return nil
+	})
+
+	msg := &Message{ID: "01HZY"}
+	done := make(chan error)
+	go func() { done <- handler(msg) }()
+	<-started
+	if err := handler(msg); !errors.Is(err, ErrInProgress) {
+		t.Errorf("处理中的重复消息不应确认, got %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if e := store.keys["mq:idem:01HZY"]; e.value != idemDone || time.Until(e.expires) < time.Hour {
+		t.Errorf("处理成功后应标记为已完成并保留 TTL: %+v", e)
+	}
+
+	// 模拟处理中进程崩溃：只留下处理中标记，到期后重投的消息可再次处理
+	store.keys["mq:idem:01HZZ"] = memoryIdempotencyEntry{value: idemProcessing + "crashed", expires: time.Now().Add(-time.Millisecond)}
+	calls := 0
+	next := Idempotent(store, IdempotencyConfig{})(func(msg *Message) error {
+		calls++
+		return nil
+	})
+	if err := next(&Message{ID: "01HZZ"}); err != nil || calls != 1 {
+		t.Errorf("崩溃遗留的处理中标记到期后应可再次处理: calls=%d err=%v", calls, err)
+	}
+}
+
+func TestIdempotent_ReleaseOnlyOwnMarker(t *testing.T) {
+	store := &memoryIdempotencyStore{keys: make(map[string]memoryIdempotencyEntry)}
+	key := "mq:idem:01J00"
+	handler := Idempotent(store, IdempotencyConfig{ProcessingTTL: time.Millisecond})(func(msg *Message) error {
+		// 处理超时，标记到期后被其他消费者重新获取
+		time.Sleep(5 * time.Millisecond)
+		store.keys[key] = memoryIdempotencyEntry{value: idemProcessing + "other", expires: time.Now().Add(time.Minute)}
+		return errors.New("处理失败")
+	})
+	if err := handler(&Message{ID: "01J00"}); err == nil {
+		t.Fatal("handler 失败时应返回错误")
+	}
+	if e := store.keys[key]; e.value != idemProcessing+"other" {
+		t.Errorf("不应删除其他消费者的处理中标记: %+v", e)
+	}
+}
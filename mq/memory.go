@@ -0,0 +1,297 @@
+package mq
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+// ErrBusClosed 总线已关闭
+var ErrBusClosed = errors.New("mq: bus closed")
+
+// MemoryBus 内存消息总线，路由语义与 RabbitMQ 一致（默认 exchange、direct、fanout、topic），
+// 用于单元测试或单进程场景，无需启动 RabbitMQ
+type MemoryBus struct {
+	mutex     sync.RWMutex
+	exchanges map[string]string          // exchange 名称 -> 类型
+	bindings  map[string][]memoryBinding // exchange 名称 -> 绑定
+	queues    map[string]*memoryQueue    // 队列名称 -> 队列
+	closed    bool
+	wg        sync.WaitGroup
+	logger    *zap.Logger
+}
+
+type memoryBinding struct {
+	queue      string
+	routingKey string
+}
+
+// NewMemoryBus 创建内存消息总线
+// logger 允许为 nil，若为 nil 则不输出日志
+func NewMemoryBus(logger *zap.Logger) *MemoryBus {
+	return &MemoryBus{
+		exchanges: make(map[string]string),
+		bindings:  make(map[string][]memoryBinding),
+		queues:    make(map[string]*memoryQueue),
+		logger:    logger,
+	}
+}
+
+// Close 关闭总线并等待消费 goroutine 退出
+func (b *MemoryBus) Close() error {
+	b.mutex.Lock()
+	if b.closed {
+		b.mutex.Unlock()
+		return nil
+	}
+	b.closed = true
+	for _, q := range b.queues {
+		q.close()
+	}
+	b.mutex.Unlock()
+	b.wg.Wait()
+	return nil
+}
+
+// QueueLen 获取队列中尚未被消费的消息数
+func (b *MemoryBus) QueueLen(queueName string) int {
+	b.mutex.RLock()
+	q := b.queues[queueName]
+	b.mutex.RUnlock()
+	if q == nil {
+		return 0
+	}
+	return q.len()
+}
+
+// declareExchangeLocked 声明 exchange，类型与已存在的不一致时报错（与 RabbitMQ 行为一致）
+func (b *MemoryBus) declareExchangeLocked(exchange, exchangeType string) error {
+	if exchange == "" {
+		return nil
+	}
+	if typ, ok := b.exchanges[exchange]; ok {
+		if exchangeType != "" && typ != exchangeType {
+			return fmt.Errorf("mq: exchange %s already declared as %s", exchange, typ)
+		}
+		return nil
+	}
+	switch exchangeType {
+	case amqp.ExchangeDirect, amqp.ExchangeFanout, amqp.ExchangeTopic:
+		b.exchanges[exchange] = exchangeType
+		return nil
+	default:
+		return fmt.Errorf("mq: memory bus does not support exchange type %q", exchangeType)
+	}
+}
+
+// PublishWithExchange 发布原始消息体
+func (b *MemoryBus) PublishWithExchange(exchange, exchangeType, routingKey string, body []byte) error {
+	return b.PublishMessage(exchange, exchangeType, routingKey, &Message{ContentType: ContentTypeText, Body: body})
+}
+
+// Publish 发布到默认 exchange，等价于 PublishWithExchange("", "", queueName, body)
+func (b *MemoryBus) Publish(queueName string, body []byte) error {
+	return b.PublishWithExchange("", "", queueName, body)
+}
+
+// PublishMessage 发布消息信封，无法路由的消息会被丢弃
+func (b *MemoryBus) PublishMessage(exchange, exchangeType, routingKey string, msg *Message) error {
+	if err := msg.fillDefaults(); err != nil {
+		return err
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return ErrBusClosed
+	}
+	if err := b.declareExchangeLocked(exchange, exchangeType); err != nil {
+		return err
+	}
+	for _, name := range b.routeLocked(exchange, routingKey) {
+		m := *msg
+		m.Headers = maps.Clone(msg.Headers)
+		m.Exchange = exchange
+		m.RoutingKey = routingKey
+		b.queues[name].push(&m)
+	}
+	return nil
+}
+
+// routeLocked 计算消息应投递的队列（去重）
+func (b *MemoryBus) routeLocked(exchange, routingKey string) []string {
+	if exchange == "" {
+		if _, ok := b.queues[routingKey]; ok {
+			return []string{routingKey}
+		}
+		return nil
+	}
+	typ := b.exchanges[exchange]
+	var targets []string
+	seen := make(map[string]struct{})
+	for _, bd := range b.bindings[exchange] {
+		matched := false
+		switch typ {
+		case amqp.ExchangeFanout:
+			matched = true
+		case amqp.ExchangeDirect:
+			matched = bd.routingKey == routingKey
+		case amqp.ExchangeTopic:
+			matched = topicMatch(bd.routingKey, routingKey)
+		}
+		if _, dup := seen[bd.queue]; matched && !dup {
+			seen[bd.queue] = struct{}{}
+			targets = append(targets, bd.queue)
+		}
+	}
+	return targets
+}
+
+// topicMatch topic 路由匹配：* 匹配一个单词，# 匹配零个或多个单词
+func topicMatch(pattern, routingKey string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(routingKey, "."))
+}
+
+func matchWords(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if matchWords(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && matchWords(pattern[1:], words[1:])
+	default:
+		return len(words) > 0 && pattern[0] == words[0] && matchWords(pattern[1:], words[1:])
+	}
+}
+
+// ConsumeWithExchange 以字符串形式消费
+func (b *MemoryBus) ConsumeWithExchange(exchange, exchangeType, queueName, routingKey string, concurrency int, handler func(msg string)) error {
+	return b.ConsumeMessage(exchange, exchangeType, queueName, routingKey, concurrency, func(msg *Message) error {
+		handler(string(msg.Body))
+		return nil
+	})
+}
+
+// Consume 从默认 exchange 消费，等价于 ConsumeWithExchange("", "", queueName, queueName, concurrency, handler)
+func (b *MemoryBus) Consume(queueName string, concurrency int, handler func(msg string)) error {
+	return b.ConsumeWithExchange("", "", queueName, queueName, concurrency, handler)
+}
+
+// DeclareQueue 声明队列并绑定到 exchange（exchange 为空时仅声明队列），无消费者时消息会在队列中堆积
+func (b *MemoryBus) DeclareQueue(exchange, exchangeType, queueName, routingKey string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	_, err := b.declareQueueLocked(exchange, exchangeType, queueName, routingKey)
+	return err
+}
+
+func (b *MemoryBus) declareQueueLocked(exchange, exchangeType, queueName, routingKey string) (*memoryQueue, error) {
+	if b.closed {
+		return nil, ErrBusClosed
+	}
+	if err := b.declareExchangeLocked(exchange, exchangeType); err != nil {
+		return nil, err
+	}
+	q, ok := b.queues[queueName]
+	if !ok {
+		q = newMemoryQueue()
+		b.queues[queueName] = q
+	}
+	if exchange != "" {
+		for _, bd := range b.bindings[exchange] {
+			if bd.queue == queueName && bd.routingKey == routingKey {
+				return q, nil
+			}
+		}
+		b.bindings[exchange] = append(b.bindings[exchange], memoryBinding{queue: queueName, routingKey: routingKey})
+	}
+	return q, nil
+}
+
+// ConsumeMessage 以消息信封形式消费，handler 返回错误时消息被丢弃（与 RabbitMQClient 的 Nack 不重新入队一致）
+func (b *MemoryBus) ConsumeMessage(exchange, exchangeType, queueName, routingKey string, concurrency int, handler MessageHandler) error {
+	b.mutex.Lock()
+	q, err := b.declareQueueLocked(exchange, exchangeType, queueName, routingKey)
+	b.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < concurrency; i++ {
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			for {
+				msg, ok := q.pop()
+				if !ok {
+					return
+				}
+				if err := handler(msg); err != nil && b.logger != nil {
+					b.logger.Error("[MemoryBus] 消息处理失败", zap.String("id", msg.ID), zap.String("queue", queueName), zap.Error(err))
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+// memoryQueue 无界 FIFO 队列
+type memoryQueue struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	items  []*Message
+	closed bool
+}
+
+func newMemoryQueue() *memoryQueue {
+	q := &memoryQueue{}
+	q.cond = sync.NewCond(&q.mutex)
+	return q
+}
+
+func (q *memoryQueue) push(m *Message) {
+	q.mutex.Lock()
+	q.items = append(q.items, m)
+	q.mutex.Unlock()
+	q.cond.Signal()
+}
+
+// pop 阻塞直到有消息或队列关闭
+func (q *memoryQueue) pop() (*Message, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return nil, false
+	}
+	m := q.items[0]
+	q.items[0] = nil
+	q.items = q.items[1:]
+	return m, true
+}
+
+func (q *memoryQueue) len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.items)
+}
+
+func (q *memoryQueue) close() {
+	q.mutex.Lock()
+	q.closed = true
+	q.mutex.Unlock()
+	q.cond.Broadcast()
+}
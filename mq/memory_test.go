@@ -0,0 +1,115 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTopicMatch(t *testing.T) {
+	cases := []struct {
+		pattern, key string
+		want         bool
+	}{
+		{"order.*", "order.created", true},
+		{"order.*", "order.created.v2", false},
+		{"order.#", "order", true},
+		{"order.#", "order.created.v2", true},
+		{"#.created", "order.created", true},
+		{"*.created.#", "order.created", true},
+		{"#", "anything.goes", true},
+		{"order.created", "order.cancelled", false},
+	}
+	for _, c := range cases {
+		if got := topicMatch(c.pattern, c.key); got != c.want {
+			t.Errorf("topicMatch(%q,%q)=%v want=%v", c.pattern, c.key, got, c.want)
+		}
+	}
+}
+
+func TestMemoryBus_Routing(t *testing.T) {
+	bus := NewMemoryBus(nil)
+	defer bus.Close()
+
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(bus.DeclareQueue("direct_ex", "direct", "q_direct", "k1"))
+	must(bus.DeclareQueue("fanout_ex", "fanout", "q_fan1", ""))
+	must(bus.DeclareQueue("fanout_ex", "fanout", "q_fan2", ""))
+	must(bus.DeclareQueue("topic_ex", "topic", "q_topic", "order.*"))
+	must(bus.DeclareQueue("", "", "q_default", ""))
+
+	must(bus.PublishWithExchange("direct_ex", "direct", "k1", []byte("a")))
+	must(bus.PublishWithExchange("direct_ex", "direct", "k2", []byte("b")))
+	must(bus.PublishWithExchange("fanout_ex", "fanout", "ignored", []byte("c")))
+	must(bus.PublishWithExchange("topic_ex", "topic", "order.created", []byte("d")))
+	must(bus.PublishWithExchange("topic_ex", "topic", "trade.created", []byte("e")))
+	must(bus.Publish("q_default", []byte("f")))
+
+	want := map[string]int{"q_direct": 1, "q_fan1": 1, "q_fan2": 1, "q_topic": 1, "q_default": 1}
+	for q, n := range want {
+		if got := bus.QueueLen(q); got != n {
+			t.Errorf("队列 %s 消息数=%d want=%d", q, got, n)
+		}
+	}
+
+	if err := bus.PublishWithExchange("direct_ex", "topic", "k1", nil); err == nil {
+		t.Error("exchange 类型不一致应报错")
+	}
+}
+
+func TestMemoryBus_ConsumeJSON(t *testing.T) {
+	var bus Bus = NewMemoryBus(nil)
+	got := make(chan testOrder, 1)
+	err := ConsumeJSON(bus, "order", "topic", "order.created", "order.#", 2, func(msg *Message, v testOrder) error {
+		if msg.CorrelationID != "corr-1" || msg.RoutingKey != "order.created" {
+			return errors.New("消息信封不符")
+		}
+		got <- v
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("启动消费者失败: %v", err)
+	}
+	if err := PublishJSON(bus, "order", "topic", "order.created", testOrder{OrderID: "A1"}, WithCorrelationID("corr-1")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	select {
+	case v := <-got:
+		if v.OrderID != "A1" {
+			t.Errorf("消费内容不符: %+v", v)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("消费超时")
+	}
+	if err := bus.Close(); err != nil {
+		t.Errorf("关闭失败: %v", err)
+	}
+	if err := bus.PublishWithExchange("order", "topic", "order.created", nil); !errors.Is(err, ErrBusClosed) {
+		t.Errorf("关闭后发布应返回 ErrBusClosed, got=%v", err)
+	}
+}
+
+func TestMemoryBus_PublishWithConfirmUnroutable(t *testing.T) {
+	bus := NewMemoryBus(nil)
+	defer bus.Close()
+	if err := bus.DeclareQueue("order", "topic", "order.created", "order.created"); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, _ := NewMessage([]byte("a"))
+	if err := bus.PublishMessageWithConfirm(context.Background(), "order", "topic", "order.created", msg); err != nil {
+		t.Fatalf("可路由消息发布失败: %v", err)
+	}
+	if err := bus.PublishMessageWithConfirm(context.Background(), "order", "topic", "order.cancelled", msg); !errors.Is(err, ErrUnroutable) {
+		t.Errorf("无匹配队列时应返回 ErrUnroutable, got %v", err)
+	}
+	if bus.QueueLen("order.created") != 1 {
+		t.Error("退回的消息不应入队")
+	}
+}
@@ -0,0 +1,371 @@
+package mq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/muchinfo/mtp2-common-lib/ulidgen"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// 常用内容类型
+const (
+	ContentTypeText     = "text/plain"
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// 追踪相关的消息头
+const (
+	HeaderTraceID = "x-trace-id"
+	HeaderSpanID  = "x-span-id"
+)
+
+// Message 消息信封，携带消息 ID、时间戳、关联 ID、内容类型、自定义消息头及投递属性
+type Message struct {
+	ID            string         // 消息 ID，默认使用 ULID
+	Timestamp     time.Time      // 消息产生时间
+	CorrelationID string         // 关联 ID
+	ReplyTo       string         // 回复队列
+	Type          string         // 消息类型
+	ContentType   string         // 内容类型
+	Headers       map[string]any // 自定义消息头
+	Persistent    bool           // 是否持久化投递
+	Priority      uint8          // 优先级（0-9），需队列设置 max_priority
+	Expiration    time.Duration  // 消息过期时间，0 表示不过期
+	Body          []byte         // 消息体
+
+	// 以下字段仅在消费端有效
+	Exchange    string // 来源 exchange
+	RoutingKey  string // 路由键
+	Redelivered bool   // 是否为重投消息
+}
+
+// MessageOption 消息选项
+type MessageOption func(*Message)
+
+// WithMessageID 指定消息 ID
+func WithMessageID(id string) MessageOption {
+	return func(m *Message) { m.ID = id }
+}
+
+// WithCorrelationID 设置关联 ID
+func WithCorrelationID(id string) MessageOption {
+	return func(m *Message) { m.CorrelationID = id }
+}
+
+// WithMessageType 设置消息类型
+func WithMessageType(typ string) MessageOption {
+	return func(m *Message) { m.Type = typ }
+}
+
+// WithHeader 设置单个消息头
+func WithHeader(key string, value any) MessageOption {
+	return func(m *Message) { m.SetHeader(key, value) }
+}
+
+// WithHeaders 批量设置消息头
+func WithHeaders(headers map[string]any) MessageOption {
+	return func(m *Message) {
+		for k, v := range headers {
+			m.SetHeader(k, v)
+		}
+	}
+}
+
+// WithTraceID 设置追踪 ID
+func WithTraceID(traceID string) MessageOption {
+	return WithHeader(HeaderTraceID, traceID)
+}
+
+// WithPersistent 设置持久化投递
+func WithPersistent() MessageOption {
+	return func(m *Message) { m.Persistent = true }
+}
+
+// WithPriority 设置优先级
+func WithPriority(priority uint8) MessageOption {
+	return func(m *Message) { m.Priority = priority }
+}
+
+// WithExpiration 设置消息过期时间
+func WithExpiration(d time.Duration) MessageOption {
+	return func(m *Message) { m.Expiration = d }
+}
+
+// NewMessage 创建消息，自动生成 ULID 消息 ID 和时间戳
+func NewMessage(body []byte, opts ...MessageOption) (*Message, error) {
+	id, err := ulidgen.GenerateULID()
+	if err != nil {
+		return nil, fmt.Errorf("generate message id: %w", err)
+	}
+	m := &Message{
+		ID:          id,
+		Timestamp:   time.Now(),
+		ContentType: ContentTypeText,
+		Body:        body,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
+}
+
+// SetHeader 设置消息头
+func (m *Message) SetHeader(key string, value any) {
+	if m.Headers == nil {
+		m.Headers = make(map[string]any)
+	}
+	m.Headers[key] = value
+}
+
+// Header 获取字符串类型的消息头，不存在时返回空字符串
+func (m *Message) Header(key string) string {
+	v, ok := m.Headers[key]
+	if !ok || v == nil {
+		return ""
+	}
+	switch s := v.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// TraceID 获取追踪 ID
+func (m *Message) TraceID() string {
+	return m.Header(HeaderTraceID)
+}
+
+// fillDefaults 补全消息 ID 和时间戳
+func (m *Message) fillDefaults() error {
+	if m.ID == "" {
+		id, err := ulidgen.GenerateULID()
+		if err != nil {
+			return fmt.Errorf("generate message id: %w", err)
+		}
+		m.ID = id
+	}
+	if m.Timestamp.IsZero() {
+		m.Timestamp = time.Now()
+	}
+	return nil
+}
+
+// toPublishing 转换为 amqp 发布结构
+func (m *Message) toPublishing() amqp.Publishing {
+	p := amqp.Publishing{
+		MessageId:     m.ID,
+		Timestamp:     m.Timestamp,
+		CorrelationId: m.CorrelationID,
+		ReplyTo:       m.ReplyTo,
+		Type:          m.Type,
+		ContentType:   m.ContentType,
+		Headers:       toTable(m.Headers),
+		Priority:      m.Priority,
+		Body:          m.Body,
+	}
+	if m.Persistent {
+		p.DeliveryMode = amqp.Persistent
+	}
+	if m.Expiration > 0 {
+		p.Expiration = strconv.FormatInt(m.Expiration.Milliseconds(), 10)
+	}
+	return p
+}
+
+// messageFromDelivery 将 amqp 投递转换为消息信封
+func messageFromDelivery(d amqp.Delivery) *Message {
+	m := &Message{
+		ID:            d.MessageId,
+		Timestamp:     d.Timestamp,
+		CorrelationID: d.CorrelationId,
+		ReplyTo:       d.ReplyTo,
+		Type:          d.Type,
+		ContentType:   d.ContentType,
+		Persistent:    d.DeliveryMode == amqp.Persistent,
+		Priority:      d.Priority,
+		Body:          d.Body,
+		Exchange:      d.Exchange,
+		RoutingKey:    d.RoutingKey,
+		Redelivered:   d.Redelivered,
+	}
+	if len(d.Headers) > 0 {
+		m.Headers = make(map[string]any, len(d.Headers))
+		for k, v := range d.Headers {
+			m.Headers[k] = v
+		}
+	}
+	if ms, err := strconv.ParseInt(d.Expiration, 10, 64); err == nil {
+		m.Expiration = time.Duration(ms) * time.Millisecond
+	}
+	return m
+}
+
+// =============================================================================
+// 编解码
+// =============================================================================
+
+// Codec 消息体编解码器
+type Codec interface {
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	// JSONCodec JSON 编解码器
+	JSONCodec Codec = jsonCodec{}
+	// ProtoCodec protobuf 编解码器，值必须实现 proto.Message
+	ProtoCodec Codec = protoCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string                { return ContentTypeJSON }
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type protoCodec struct{}
+
+func (protoCodec) ContentType() string { return ContentTypeProtobuf }
+
+func (protoCodec) Marshal(v any) ([]byte, error) {
+	pm, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("mq: %T does not implement proto.Message", v)
+	}
+	return proto.Marshal(pm)
+}
+
+func (protoCodec) Unmarshal(data []byte, v any) error {
+	pm, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("mq: %T does not implement proto.Message", v)
+	}
+	return proto.Unmarshal(data, pm)
+}
+
+// codecFor 根据内容类型选择编解码器，未知类型按 JSON 处理
+func codecFor(contentType string) Codec {
+	if contentType == ContentTypeProtobuf {
+		return ProtoCodec
+	}
+	return JSONCodec
+}
+
+// EncodeMessage 使用指定编解码器编码 v 并创建消息
+func EncodeMessage(codec Codec, v any, opts ...MessageOption) (*Message, error) {
+	body, err := codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	m, err := NewMessage(body, opts...)
+	if err != nil {
+		return nil, err
+	}
+	m.ContentType = codec.ContentType()
+	return m, nil
+}
+
+// Decode 按消息的内容类型解码消息体
+// T 为 protobuf 消息指针（如 *pb.Order）时会自动分配对象
+func Decode[T any](msg *Message) (T, error) {
+	return decodeWith[T](codecFor(msg.ContentType), msg)
+}
+
+func decodeWith[T any](codec Codec, msg *Message) (T, error) {
+	var v T
+	var target any = &v
+	if _, ok := any(v).(proto.Message); ok {
+		typ := reflect.TypeOf(v)
+		if typ.Kind() != reflect.Pointer {
+			return v, errors.New("mq: protobuf message type must be a pointer")
+		}
+		v = reflect.New(typ.Elem()).Interface().(T)
+		target = v
+	}
+	if err := codec.Unmarshal(msg.Body, target); err != nil {
+		return v, fmt.Errorf("decode message %s: %w", msg.ID, err)
+	}
+	return v, nil
+}
+
+// =============================================================================
+// 发布与消费
+// =============================================================================
+
+// MessageHandler 消息处理函数，返回 nil 时确认消息，返回错误时拒绝消息（不重新入队）
+type MessageHandler func(msg *Message) error
+
+// PublishMessage 发布消息信封，未设置 ID/时间戳时自动补全
+func (c *RabbitMQClient) PublishMessage(exchange, exchangeType, routingKey string, msg *Message) error {
+	if err := msg.fillDefaults(); err != nil {
+		return err
+	}
+	return c.publish(exchange, exchangeType, routingKey, msg.toPublishing())
+}
+
+// ConsumeMessage 以消息信封形式消费，handler 返回 nil 时 Ack，否则 Nack（不重新入队，可配合死信队列）
+func (c *RabbitMQClient) ConsumeMessage(exchange, exchangeType, queueName, routingKey string, concurrency int, handler MessageHandler) error {
+	return c.consume(exchange, exchangeType, queueName, routingKey, concurrency, func(d amqp.Delivery) {
+		msg := messageFromDelivery(d)
+		if err := handler(msg); err != nil {
+			if c.logger != nil {
+				c.logger.Error("[RabbitMQ] 消息处理失败", zap.String("id", msg.ID), zap.String("queue", queueName), zap.Error(err))
+			}
+			d.Nack(false, false)
+			return
+		}
+		d.Ack(false)
+	})
+}
+
+// PublishJSON 以 JSON 编码发布 v
+func PublishJSON[T any](c Publisher, exchange, exchangeType, routingKey string, v T, opts ...MessageOption) error {
+	msg, err := EncodeMessage(JSONCodec, v, opts...)
+	if err != nil {
+		return err
+	}
+	return c.PublishMessage(exchange, exchangeType, routingKey, msg)
+}
+
+// PublishProto 以 protobuf 编码发布 m
+func PublishProto(c Publisher, exchange, exchangeType, routingKey string, m proto.Message, opts ...MessageOption) error {
+	msg, err := EncodeMessage(ProtoCodec, m, opts...)
+	if err != nil {
+		return err
+	}
+	return c.PublishMessage(exchange, exchangeType, routingKey, msg)
+}
+
+// ConsumeJSON 消费并按 JSON 解码为 T，解码失败的消息会被拒绝
+func ConsumeJSON[T any](c Subscriber, exchange, exchangeType, queueName, routingKey string, concurrency int, handler func(msg *Message, v T) error) error {
+	return c.ConsumeMessage(exchange, exchangeType, queueName, routingKey, concurrency, func(msg *Message) error {
+		v, err := decodeWith[T](JSONCodec, msg)
+		if err != nil {
+			return err
+		}
+		return handler(msg, v)
+	})
+}
+
+// ConsumeProto 消费并按 protobuf 解码为 T（如 *pb.Order），解码失败的消息会被拒绝
+func ConsumeProto[T proto.Message](c Subscriber, exchange, exchangeType, queueName, routingKey string, concurrency int, handler func(msg *Message, v T) error) error {
+	return c.ConsumeMessage(exchange, exchangeType, queueName, routingKey, concurrency, func(msg *Message) error {
+		v, err := decodeWith[T](ProtoCodec, msg)
+		if err != nil {
+			return err
+		}
+		return handler(msg, v)
+	})
+}
@@ -0,0 +1,90 @@
+package mq
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type testOrder struct {
+	OrderID string  `json:"order_id"`
+	Price   float64 `json:"price"`
+}
+
+// toDelivery 模拟服务端投递
+func toDelivery(p amqp.Publishing) amqp.Delivery {
+	return amqp.Delivery{
+		Headers:       p.Headers,
+		ContentType:   p.ContentType,
+		DeliveryMode:  p.DeliveryMode,
+		Priority:      p.Priority,
+		CorrelationId: p.CorrelationId,
+		ReplyTo:       p.ReplyTo,
+		Expiration:    p.Expiration,
+		MessageId:     p.MessageId,
+		Timestamp:     p.Timestamp,
+		Type:          p.Type,
+		Body:          p.Body,
+	}
+}
+
+func TestMessage_EnvelopeRoundTrip(t *testing.T) {
+	msg, err := EncodeMessage(JSONCodec, testOrder{OrderID: "A001", Price: 12.5},
+		WithCorrelationID("corr-1"),
+		WithTraceID("trace-1"),
+		WithHeader("tenant", "t1"),
+		WithPersistent(),
+		WithPriority(5),
+		WithExpiration(3*time.Second),
+	)
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	if len(msg.ID) != 26 {
+		t.Errorf("消息 ID 应为 ULID, got=%s", msg.ID)
+	}
+
+	p := msg.toPublishing()
+	if p.DeliveryMode != amqp.Persistent || p.Expiration != "3000" || p.ContentType != ContentTypeJSON {
+		t.Fatalf("发布属性不符: %+v", p)
+	}
+
+	got := messageFromDelivery(toDelivery(p))
+	if got.ID != msg.ID || got.CorrelationID != "corr-1" || got.TraceID() != "trace-1" || got.Header("tenant") != "t1" {
+		t.Errorf("消息信封不符: %+v", got)
+	}
+	if !got.Persistent || got.Priority != 5 || got.Expiration != 3*time.Second {
+		t.Errorf("投递属性不符: %+v", got)
+	}
+
+	order, err := Decode[testOrder](got)
+	if err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	if order.OrderID != "A001" || order.Price != 12.5 {
+		t.Errorf("解码结果不符: %+v", order)
+	}
+}
+
+func TestMessage_ProtoCodec(t *testing.T) {
+	msg, err := EncodeMessage(ProtoCodec, wrapperspb.String("hello"))
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	if msg.ContentType != ContentTypeProtobuf {
+		t.Errorf("内容类型不符: %s", msg.ContentType)
+	}
+	v, err := Decode[*wrapperspb.StringValue](msg)
+	if err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	if v.GetValue() != "hello" {
+		t.Errorf("解码结果不符: %s", v.GetValue())
+	}
+
+	if _, err := EncodeMessage(ProtoCodec, testOrder{}); err == nil {
+		t.Error("非 proto.Message 应编码失败")
+	}
+}
@@ -2,6 +2,8 @@ package mq
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -9,6 +11,11 @@ import (
 	"go.uber.org/zap"
 )
 
+var (
+	ErrNotConfirmed = errors.New("mq: message not confirmed by broker")          // 服务端未确认（nack）消息
+	ErrUnroutable   = errors.New("mq: message returned as unroutable by broker") // 消息没有匹配的队列，被服务端退回
+)
+
 // RabbitMQClient 支持并发安全的连接和通道管理
 type RabbitMQClient struct {
 	url    string
@@ -21,6 +28,15 @@ type RabbitMQClient struct {
 	predeclareExchange     bool
 	predeclaredExchange    string
 	predeclaredExchangeTyp string
+
+	// 声明式拓扑，重连后自动重新声明
+	topology Topology
+	// 延迟投递状态，未启用时为 nil
+	delay *delayState
+
+	// RPC 客户端通道，首次 Call 时创建
+	rpcMutex sync.Mutex
+	rpc      *rpcClient
 }
 
 // NewRabbitMQClient 创建客户端
@@ -65,9 +81,17 @@ func (c *RabbitMQClient) connectWithRetry() error {
 	for i := range 10 { // 最多重试10次
 		c.conn, err = amqp.Dial(c.url)
 		if err == nil {
-			return nil
-		}
-		if c.logger != nil {
+			// 重新声明拓扑，保证重连后 exchange/队列/绑定仍然存在
+			if err = c.declareTopologyLocked(); err == nil {
+				return nil
+			}
+			// 拓扑声明失败时关闭连接，在同一退避循环中重试，避免留下未声明拓扑的连接
+			c.conn.Close()
+			c.conn = nil
+			if c.logger != nil {
+				c.logger.Warn("[RabbitMQ] 拓扑声明失败，稍后重试", zap.Int("retry", i+1), zap.Error(err))
+			}
+		} else if c.logger != nil {
 			c.logger.Warn("[RabbitMQ] 连接失败，稍后重试", zap.Int("retry", i+1), zap.Error(err))
 		}
 		time.Sleep(time.Duration(2<<i) * time.Millisecond) // 指数退避
@@ -90,6 +114,13 @@ func (c *RabbitMQClient) Channel() (*amqp.Channel, error) {
 	return c.conn.Channel()
 }
 
+// IsConnected 连接是否可用，供健康检查使用；断线后下次 Channel 调用时自动重连
+func (c *RabbitMQClient) IsConnected() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return !c.closed && c.conn != nil && !c.conn.IsClosed()
+}
+
 // Close 关闭连接
 func (c *RabbitMQClient) Close() error {
 	c.mutex.Lock()
@@ -103,6 +134,14 @@ func (c *RabbitMQClient) Close() error {
 
 // Publish 支持自定义 exchange 和 routingKey，兼容原有用法
 func (c *RabbitMQClient) PublishWithExchange(exchange, exchangeType, routingKey string, body []byte) error {
+	return c.publish(exchange, exchangeType, routingKey, amqp.Publishing{
+		ContentType: "text/plain",
+		Body:        body,
+	})
+}
+
+// publish 发送消息，失败时重新获取通道重试
+func (c *RabbitMQClient) publish(exchange, exchangeType, routingKey string, msg amqp.Publishing) error {
 	var lastErr error
 	for i := 0; i < 3; i++ {
 		ch, err := c.Channel()
@@ -111,28 +150,16 @@ func (c *RabbitMQClient) PublishWithExchange(exchange, exchangeType, routingKey
 			time.Sleep(500 * time.Millisecond)
 			continue
 		}
-		defer ch.Close()
-		// 高性能模式：只在初始化声明 Exchange
-		if c.predeclareExchange && exchange == c.predeclaredExchange {
-			// 不再声明 Exchange
-		} else if exchange != "" {
-			// 兼容模式：每次声明 Exchange
-			err = ch.ExchangeDeclare(
-				exchange, exchangeType, false, false, false, false, nil,
-			)
-			if err != nil {
-				lastErr = err
-				time.Sleep(500 * time.Millisecond)
-				continue
-			}
+		if err = c.prepareExchange(ch, exchange, exchangeType); err != nil {
+			ch.Close()
+			lastErr = err
+			time.Sleep(500 * time.Millisecond)
+			continue
 		}
 		err = ch.PublishWithContext(context.Background(),
-			exchange, routingKey, false, false,
-			amqp.Publishing{
-				ContentType: "text/plain",
-				Body:        body,
-			},
+			exchange, routingKey, false, false, msg,
 		)
+		ch.Close()
 		if err == nil {
 			return nil
 		}
@@ -142,6 +169,53 @@ func (c *RabbitMQClient) PublishWithExchange(exchange, exchangeType, routingKey
 	return lastErr
 }
 
+// publishConfirmed 以 publisher confirm 模式发布，服务端确认后才返回 nil。
+// 以 mandatory 方式发布，没有匹配队列的消息会被退回并返回 ErrUnroutable，而不是确认后静默丢弃
+func (c *RabbitMQClient) publishConfirmed(ctx context.Context, exchange, exchangeType, routingKey string, msg amqp.Publishing) error {
+	ch, err := c.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+	if err := ch.Confirm(false); err != nil {
+		return fmt.Errorf("enable confirm mode: %w", err)
+	}
+	if err := c.prepareExchange(ch, exchange, exchangeType); err != nil {
+		return err
+	}
+	returns := ch.NotifyReturn(make(chan amqp.Return, 1))
+	dc, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, true, false, msg)
+	if err != nil {
+		return err
+	}
+	acked, err := dc.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return ErrNotConfirmed
+	}
+	// 服务端先发送 basic.return 再发送 ack，确认到达时退回消息已在通道中
+	select {
+	case r := <-returns:
+		return fmt.Errorf("%w: %d %s", ErrUnroutable, r.ReplyCode, r.ReplyText)
+	default:
+	}
+	return nil
+}
+
+// prepareExchange 发布前按需声明 Exchange
+func (c *RabbitMQClient) prepareExchange(ch *amqp.Channel, exchange, exchangeType string) error {
+	// 高性能模式：只在初始化声明 Exchange
+	if c.exchangeDeclared(exchange) || exchange == "" {
+		return nil
+	}
+	// 兼容模式：每次声明 Exchange
+	return ch.ExchangeDeclare(
+		exchange, exchangeType, false, false, false, false, nil,
+	)
+}
+
 // 兼容原有用法：Publish(queueName, body) 等价于 PublishWithExchange("", "", queueName, body)
 func (c *RabbitMQClient) Publish(queueName string, body []byte) error {
 	return c.PublishWithExchange("", "", queueName, body)
@@ -149,6 +223,13 @@ func (c *RabbitMQClient) Publish(queueName string, body []byte) error {
 
 // Consume 支持自定义 exchange 和 routingKey，兼容原有用法
 func (c *RabbitMQClient) ConsumeWithExchange(exchange, exchangeType, queueName, routingKey string, concurrency int, handler func(msg string)) error {
+	return c.consume(exchange, exchangeType, queueName, routingKey, concurrency, func(d amqp.Delivery) {
+		handler(string(d.Body))
+	})
+}
+
+// consume 启动 concurrency 个消费 goroutine，通道异常时自动重建
+func (c *RabbitMQClient) consume(exchange, exchangeType, queueName, routingKey string, concurrency int, handle func(d amqp.Delivery)) error {
 	var wg sync.WaitGroup
 	for i := 0; i < concurrency; i++ {
 		wg.Add(1)
@@ -164,7 +245,7 @@ func (c *RabbitMQClient) ConsumeWithExchange(exchange, exchangeType, queueName,
 					continue
 				}
 				// 高性能模式：只在初始化声明 Exchange
-				if c.predeclareExchange && exchange == c.predeclaredExchange {
+				if c.exchangeDeclared(exchange) {
 					// 不再声明 Exchange
 				} else if exchange != "" {
 					// 兼容模式：每次声明 Exchange
@@ -180,10 +261,12 @@ func (c *RabbitMQClient) ConsumeWithExchange(exchange, exchangeType, queueName,
 						continue
 					}
 				}
-				// 声明队列并绑定到 exchange
-				_, err = ch.QueueDeclare(
-					queueName, false, false, false, false, nil,
-				)
+				// 声明队列并绑定到 exchange（拓扑中已声明的队列不再重复声明，避免参数不一致）
+				if !c.queueDeclared(queueName) {
+					_, err = ch.QueueDeclare(
+						queueName, false, false, false, false, nil,
+					)
+				}
 				if err != nil {
 					ch.Close()
 					if c.logger != nil {
@@ -217,7 +300,7 @@ func (c *RabbitMQClient) ConsumeWithExchange(exchange, exchangeType, queueName,
 					continue
 				}
 				for d := range msgs {
-					handler(string(d.Body))
+					handle(d)
 				}
 				ch.Close()
 				if c.logger != nil {
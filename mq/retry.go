@@ -0,0 +1,195 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+// 重试相关的消息头
+const (
+	HeaderRetryAttempt = "x-retry-attempt" // 已重试次数
+	HeaderRetryError   = "x-retry-error"   // 最近一次处理失败的错误信息
+	HeaderRetryQueue   = "x-retry-queue"   // 原始消费队列
+)
+
+// RetryPolicy 消费重试策略
+//
+// 处理失败的消息按 Delays 依次发布到分级重试队列（如 <queue>.retry.1s），
+// 重试队列设置了队列级 TTL，到期后经默认 exchange 死信回原队列重新消费；
+// 超过最后一级仍失败的消息进入停车队列（parking lot），等待人工处理。
+// 转发以 publisher confirm 方式发布，服务端确认后才确认原消息；转发失败时等待 RequeueDelay 后重新入队。
+type RetryPolicy struct {
+	Delays         []time.Duration // 重试分级，默认 1s/10s/1m
+	RetryPrefix    string          // 重试队列名前缀，默认 <queue>.retry.
+	ParkingQueue   string          // 停车队列，默认 <queue>.parking
+	PublishTimeout time.Duration   // 转发到重试/停车队列时等待服务端确认的超时，默认 5 秒
+	RequeueDelay   time.Duration   // 转发失败后重新入队前的等待时间，默认 1 秒，避免转发持续失败时反复重投
+}
+
+// withDefaults 按消费队列填充默认值
+func (p RetryPolicy) withDefaults(queueName string) RetryPolicy {
+	if len(p.Delays) == 0 {
+		p.Delays = []time.Duration{time.Second, 10 * time.Second, time.Minute}
+	}
+	if p.RetryPrefix == "" {
+		p.RetryPrefix = queueName + ".retry."
+	}
+	if p.ParkingQueue == "" {
+		p.ParkingQueue = queueName + ".parking"
+	}
+	if p.PublishTimeout <= 0 {
+		p.PublishTimeout = 5 * time.Second
+	}
+	if p.RequeueDelay <= 0 {
+		p.RequeueDelay = time.Second
+	}
+	return p
+}
+
+// retryQueue 第 i 级重试队列名
+func (p RetryPolicy) retryQueue(i int) string {
+	return p.RetryPrefix + p.Delays[i].String()
+}
+
+// next 根据已重试次数决定下一步：返回重试队列名，或 park=true 表示进入停车队列
+func (p RetryPolicy) next(attempt int) (queue string, park bool) {
+	if attempt < 0 {
+		attempt = 0
+	}
+	if attempt >= len(p.Delays) {
+		return p.ParkingQueue, true
+	}
+	return p.retryQueue(attempt), false
+}
+
+// topology 重试队列与停车队列
+func (p RetryPolicy) topology(queueName string) Topology {
+	var topo Topology
+	for i, d := range p.Delays {
+		topo.Queues = append(topo.Queues, QueueConfig{
+			Name:                 p.retryQueue(i),
+			Durable:              true,
+			MessageTTL:           d,
+			DeadLetterRoutingKey: queueName,
+			// 死信到默认 exchange（空字符串），按路由键回到原队列
+			Args: map[string]any{"x-dead-letter-exchange": ""},
+		})
+	}
+	topo.Queues = append(topo.Queues, QueueConfig{Name: p.ParkingQueue, Durable: true})
+	return topo
+}
+
+// RetryStats 重试统计，可在运行时读取
+type RetryStats struct {
+	succeeded atomic.Int64
+	failed    atomic.Int64
+	retried   atomic.Int64
+	parked    atomic.Int64
+}
+
+// RetryMetrics 重试统计快照
+type RetryMetrics struct {
+	Succeeded int64 // 处理成功数
+	Failed    int64 // 处理失败数（含重试和进入停车队列）
+	Retried   int64 // 发布到重试队列的次数
+	Parked    int64 // 进入停车队列的消息数
+}
+
+// Snapshot 获取统计快照
+func (s *RetryStats) Snapshot() RetryMetrics {
+	return RetryMetrics{
+		Succeeded: s.succeeded.Load(),
+		Failed:    s.failed.Load(),
+		Retried:   s.retried.Load(),
+		Parked:    s.parked.Load(),
+	}
+}
+
+// headerInt 读取整数类型的消息头
+func headerInt(headers map[string]any, key string) int {
+	switch v := headers[key].(type) {
+	case int:
+		return v
+	case int8:
+		return int(v)
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case uint8:
+		return int(v)
+	case uint16:
+		return int(v)
+	case uint32:
+		return int(v)
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
+
+// ConsumeWithRetry 在 ConsumeWithExchange 基础上增加失败重试：
+// handler 返回错误时消息进入分级重试队列，重试耗尽后进入停车队列，返回的统计可用于监控
+func (c *RabbitMQClient) ConsumeWithRetry(exchange, exchangeType, queueName, routingKey string, concurrency int, policy RetryPolicy, handler MessageHandler) (*RetryStats, error) {
+	policy = policy.withDefaults(queueName)
+	if err := c.DeclareTopology(policy.topology(queueName)); err != nil {
+		return nil, err
+	}
+	stats := &RetryStats{}
+	err := c.consume(exchange, exchangeType, queueName, routingKey, concurrency, func(d amqp.Delivery) {
+		msg := messageFromDelivery(d)
+		herr := handler(msg)
+		if herr == nil {
+			stats.succeeded.Add(1)
+			d.Ack(false)
+			return
+		}
+		stats.failed.Add(1)
+
+		attempt := headerInt(msg.Headers, HeaderRetryAttempt)
+		target, park := policy.next(attempt)
+		msg.SetHeader(HeaderRetryAttempt, int32(attempt+1))
+		msg.SetHeader(HeaderRetryError, herr.Error())
+		msg.SetHeader(HeaderRetryQueue, queueName)
+		msg.Expiration = 0 // 由重试队列 TTL 控制等待时间
+		ctx, cancel := context.WithTimeout(context.Background(), policy.PublishTimeout)
+		err := c.publishConfirmed(ctx, "", "", target, msg.toPublishing())
+		cancel()
+		if err != nil {
+			// 转发未确认时不能确认原消息；重新入队会立即重投，先等待以免形成热循环
+			if c.logger != nil {
+				c.logger.Error("[RabbitMQ] 重试消息转发失败，稍后重新入队", zap.String("id", msg.ID), zap.String("target", target), zap.Error(err))
+			}
+			time.Sleep(policy.RequeueDelay)
+			d.Nack(false, true)
+			return
+		}
+		if park {
+			stats.parked.Add(1)
+			if c.logger != nil {
+				c.logger.Error("[RabbitMQ] 消息重试耗尽，进入停车队列",
+					zap.String("id", msg.ID), zap.String("queue", queueName), zap.Int("attempt", attempt), zap.Error(herr))
+			}
+		} else {
+			stats.retried.Add(1)
+			if c.logger != nil {
+				c.logger.Warn("[RabbitMQ] 消息处理失败，进入重试队列",
+					zap.String("id", msg.ID), zap.String("retry_queue", target), zap.Int("attempt", attempt+1), zap.Error(herr))
+			}
+		}
+		d.Ack(false)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("consume %s: %w", queueName, err)
+	}
+	return stats, nil
+}
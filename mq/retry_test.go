@@ -0,0 +1,58 @@
+package mq
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Next(t *testing.T) {
+	p := RetryPolicy{}.withDefaults("settle")
+	cases := []struct {
+		attempt int
+		queue   string
+		park    bool
+	}{
+		{0, "settle.retry.1s", false},
+		{1, "settle.retry.10s", false},
+		{2, "settle.retry.1m0s", false},
+		{3, "settle.parking", true},
+		{10, "settle.parking", true},
+	}
+	if p.PublishTimeout != 5*time.Second || p.RequeueDelay != time.Second {
+		t.Errorf("默认超时不符: %+v", p)
+	}
+	for _, c := range cases {
+		queue, park := p.next(c.attempt)
+		if queue != c.queue || park != c.park {
+			t.Errorf("next(%d)=(%s,%v) want=(%s,%v)", c.attempt, queue, park, c.queue, c.park)
+		}
+	}
+}
+
+func TestRetryPolicy_Topology(t *testing.T) {
+	p := RetryPolicy{Delays: []time.Duration{5 * time.Second}, ParkingQueue: "dead"}.withDefaults("settle")
+	topo := p.topology("settle")
+	if err := topo.Validate(); err != nil {
+		t.Fatalf("拓扑校验失败: %v", err)
+	}
+	if len(topo.Queues) != 2 || topo.Queues[1].Name != "dead" {
+		t.Fatalf("队列不符: %+v", topo.Queues)
+	}
+	args := topo.Queues[0].arguments()
+	if v, ok := args["x-dead-letter-exchange"]; !ok || v != "" {
+		t.Errorf("重试队列应死信到默认 exchange: %v", args)
+	}
+	if args["x-dead-letter-routing-key"] != "settle" || args["x-message-ttl"] != int64(5000) {
+		t.Errorf("重试队列参数不符: %v", args)
+	}
+}
+
+func TestHeaderInt(t *testing.T) {
+	headers := map[string]any{"a": int32(3), "b": int64(4), "c": "5", "d": 1.5}
+	if headerInt(headers, "a") != 3 || headerInt(headers, "b") != 4 || headerInt(headers, "c") != 5 {
+		t.Error("整数消息头解析不符")
+	}
+	if headerInt(headers, "d") != 0 || headerInt(headers, "missing") != 0 {
+		t.Error("非整数消息头应返回 0")
+	}
+}
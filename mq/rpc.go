@@ -0,0 +1,248 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/muchinfo/mtp2-common-lib/ulidgen"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+// 直接回复（direct reply-to）伪队列
+const replyToQueue = "amq.rabbitmq.reply-to"
+
+// HeaderRPCError 服务端处理失败时在回复中携带的错误信息
+const HeaderRPCError = "x-rpc-error"
+
+// DefaultCallTimeout ctx 未设置截止时间时 Call 的默认超时
+const DefaultCallTimeout = 30 * time.Second
+
+var (
+	// ErrNoRoute 请求无法路由到任何队列（没有服务端）
+	ErrNoRoute = errors.New("mq rpc: request unroutable")
+	// ErrRPCChannelClosed 等待回复期间通道关闭
+	ErrRPCChannelClosed = errors.New("mq rpc: reply channel closed")
+)
+
+// RPCError 服务端返回的业务错误
+type RPCError struct {
+	Message string
+}
+
+func (e *RPCError) Error() string {
+	return "mq rpc: remote error: " + e.Message
+}
+
+// RPCHandler RPC 服务端处理函数，返回的字节作为回复消息体，返回错误时回复 x-rpc-error
+type RPCHandler func(ctx context.Context, req *Message) ([]byte, error)
+
+// rpcReply 回复或错误
+type rpcReply struct {
+	msg *Message
+	err error
+}
+
+// rpcClient 基于 direct reply-to 的 RPC 客户端通道
+type rpcClient struct {
+	ch      *amqp.Channel
+	mutex   sync.Mutex
+	pending map[string]chan rpcReply
+	closed  bool
+}
+
+// rpcChannel 获取（必要时重建）RPC 通道：在同一通道上消费 direct reply-to 并发布请求
+func (c *RabbitMQClient) rpcChannel() (*rpcClient, error) {
+	c.rpcMutex.Lock()
+	defer c.rpcMutex.Unlock()
+	if c.rpc != nil && !c.rpc.isClosed() {
+		return c.rpc, nil
+	}
+	ch, err := c.Channel()
+	if err != nil {
+		return nil, err
+	}
+	replies, err := ch.Consume(replyToQueue, "", true, false, false, false, nil)
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+	returns := ch.NotifyReturn(make(chan amqp.Return, 16))
+	rc := &rpcClient{ch: ch, pending: make(map[string]chan rpcReply)}
+	go rc.dispatch(replies, returns)
+	c.rpc = rc
+	return rc, nil
+}
+
+// dispatch 按 correlation ID 分发回复，通道关闭后通知所有等待者
+func (rc *rpcClient) dispatch(replies <-chan amqp.Delivery, returns <-chan amqp.Return) {
+	for replies != nil || returns != nil {
+		select {
+		case d, ok := <-replies:
+			if !ok {
+				replies = nil
+				continue
+			}
+			rc.deliver(d.CorrelationId, rpcReply{msg: messageFromDelivery(d)})
+		case r, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			rc.deliver(r.CorrelationId, rpcReply{err: fmt.Errorf("%w: %s", ErrNoRoute, r.ReplyText)})
+		}
+	}
+	rc.mutex.Lock()
+	rc.closed = true
+	for id, waiter := range rc.pending {
+		waiter <- rpcReply{err: ErrRPCChannelClosed}
+		delete(rc.pending, id)
+	}
+	rc.mutex.Unlock()
+}
+
+func (rc *rpcClient) deliver(correlationID string, reply rpcReply) {
+	rc.mutex.Lock()
+	waiter, ok := rc.pending[correlationID]
+	delete(rc.pending, correlationID)
+	rc.mutex.Unlock()
+	if ok {
+		waiter <- reply
+	}
+}
+
+func (rc *rpcClient) isClosed() bool {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	return rc.closed
+}
+
+// Call 发送 RPC 请求并等待回复，使用 direct reply-to 与 correlation ID 匹配回复
+// ctx 未设置截止时间时使用 DefaultCallTimeout；请求的过期时间与剩余超时一致，超时的请求不会再被服务端处理
+func (c *RabbitMQClient) Call(ctx context.Context, exchange, routingKey string, body []byte) ([]byte, error) {
+	msg, err := NewMessage(body)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.CallMessage(ctx, exchange, routingKey, msg)
+	if err != nil {
+		return nil, err
+	}
+	return reply.Body, nil
+}
+
+// CallMessage 以消息信封形式发送 RPC 请求并返回回复消息
+func (c *RabbitMQClient) CallMessage(ctx context.Context, exchange, routingKey string, msg *Message) (*Message, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultCallTimeout)
+		defer cancel()
+	}
+	if err := msg.fillDefaults(); err != nil {
+		return nil, err
+	}
+	if msg.CorrelationID == "" {
+		id, err := ulidgen.GenerateULID()
+		if err != nil {
+			return nil, err
+		}
+		msg.CorrelationID = id
+	}
+	msg.ReplyTo = replyToQueue
+
+	rc, err := c.rpcChannel()
+	if err != nil {
+		return nil, err
+	}
+	waiter := make(chan rpcReply, 1)
+	rc.mutex.Lock()
+	if rc.closed {
+		rc.mutex.Unlock()
+		return nil, ErrRPCChannelClosed
+	}
+	rc.pending[msg.CorrelationID] = waiter
+	rc.mutex.Unlock()
+	defer func() {
+		rc.mutex.Lock()
+		delete(rc.pending, msg.CorrelationID)
+		rc.mutex.Unlock()
+	}()
+
+	pub := msg.toPublishing()
+	if deadline, ok := ctx.Deadline(); ok {
+		if remain := time.Until(deadline); remain > 0 {
+			pub.Expiration = strconv.FormatInt(remain.Milliseconds(), 10)
+		}
+	}
+	// mandatory=true：无法路由时服务端退回，调用方立即得到 ErrNoRoute
+	if err := rc.ch.PublishWithContext(ctx, exchange, routingKey, true, false, pub); err != nil {
+		return nil, err
+	}
+
+	select {
+	case reply := <-waiter:
+		if reply.err != nil {
+			return nil, reply.err
+		}
+		if e := reply.msg.Header(HeaderRPCError); e != "" {
+			return nil, &RPCError{Message: e}
+		}
+		return reply.msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Serve 从默认 exchange 下的队列接收 RPC 请求并自动回复，等价于 ServeWithExchange("", "", queueName, queueName, 1, handler)
+func (c *RabbitMQClient) Serve(queueName string, handler RPCHandler) error {
+	return c.ServeWithExchange("", "", queueName, queueName, 1, handler)
+}
+
+// ServeWithExchange 启动 RPC 服务端，handler 的返回值按请求的 ReplyTo/CorrelationID 自动回复
+func (c *RabbitMQClient) ServeWithExchange(exchange, exchangeType, queueName, routingKey string, concurrency int, handler RPCHandler) error {
+	return c.consume(exchange, exchangeType, queueName, routingKey, concurrency, func(d amqp.Delivery) {
+		req := messageFromDelivery(d)
+		ctx := context.Background()
+		if req.Expiration > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, req.Expiration)
+			defer cancel()
+		}
+
+		body, herr := handler(ctx, req)
+		if req.ReplyTo == "" {
+			if c.logger != nil {
+				c.logger.Warn("[RabbitMQ] RPC 请求缺少 ReplyTo，不回复", zap.String("id", req.ID), zap.String("queue", queueName))
+			}
+			d.Ack(false)
+			return
+		}
+
+		reply := &Message{
+			CorrelationID: req.CorrelationID,
+			ContentType:   req.ContentType,
+			Body:          body,
+		}
+		if herr != nil {
+			reply.Body = nil
+			reply.SetHeader(HeaderRPCError, herr.Error())
+		}
+		if err := reply.fillDefaults(); err != nil {
+			d.Nack(false, false)
+			return
+		}
+		if err := c.publish("", "", req.ReplyTo, reply.toPublishing()); err != nil {
+			if c.logger != nil {
+				c.logger.Error("[RabbitMQ] RPC 回复失败", zap.String("id", req.ID), zap.Error(err))
+			}
+			// 调用方可能已超时，回复失败不重新入队，避免重复执行
+			d.Nack(false, false)
+			return
+		}
+		d.Ack(false)
+	})
+}
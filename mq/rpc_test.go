@@ -0,0 +1,58 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestRabbitMQClient_CallServe(t *testing.T) {
+	url := os.Getenv("RABBITMQ_URL")
+	if url == "" {
+		t.Skip("未设置 RABBITMQ_URL，跳过测试")
+	}
+	logger, _ := zap.NewDevelopment()
+	client, err := NewRabbitMQClient(url, logger)
+	if err != nil {
+		t.Fatalf("连接 RabbitMQ 失败: %v", err)
+	}
+	defer client.Close()
+
+	queue := "test_rpc_upper"
+	err = client.Serve(queue, func(ctx context.Context, req *Message) ([]byte, error) {
+		if string(req.Body) == "boom" {
+			return nil, errors.New("bad request")
+		}
+		return []byte(strings.ToUpper(string(req.Body))), nil
+	})
+	if err != nil {
+		t.Fatalf("启动 RPC 服务失败: %v", err)
+	}
+	time.Sleep(500 * time.Millisecond) // 等待服务端声明队列
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := client.Call(ctx, "", queue, []byte("hello"))
+	if err != nil {
+		t.Fatalf("RPC 调用失败: %v", err)
+	}
+	if string(reply) != "HELLO" {
+		t.Errorf("回复不符: %s", reply)
+	}
+
+	_, err = client.Call(ctx, "", queue, []byte("boom"))
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Message != "bad request" {
+		t.Errorf("应返回服务端错误, got=%v", err)
+	}
+
+	_, err = client.Call(ctx, "", "test_rpc_no_such_queue", []byte("x"))
+	if !errors.Is(err, ErrNoRoute) {
+		t.Errorf("无服务端时应返回 ErrNoRoute, got=%v", err)
+	}
+}
@@ -0,0 +1,331 @@
+package mq
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+// Topology RabbitMQ 拓扑描述（exchange、队列、绑定），可直接从 viper YAML 配置加载
+//
+// 示例配置：
+//
+//	rabbitmq:
+//	  topology:
+//	    exchanges:
+//	      - name: order
+//	        type: topic
+//	        durable: true
+//	    queues:
+//	      - name: order.created
+//	        durable: true
+//	        queue_type: quorum
+//	        message_ttl: 10m
+//	        dead_letter_exchange: order.dlx
+//	    bindings:
+//	      - exchange: order
+//	        queue: order.created
+//	        routing_key: order.created.#
+type Topology struct {
+	Exchanges []ExchangeConfig `mapstructure:"exchanges"`
+	Queues    []QueueConfig    `mapstructure:"queues"`
+	Bindings  []BindingConfig  `mapstructure:"bindings"`
+}
+
+// ExchangeConfig exchange 声明配置
+type ExchangeConfig struct {
+	Name       string         `mapstructure:"name"`        // exchange 名称
+	Type       string         `mapstructure:"type"`        // direct / fanout / topic / headers，默认 direct
+	Durable    bool           `mapstructure:"durable"`     // 是否持久化
+	AutoDelete bool           `mapstructure:"auto_delete"` // 无绑定时是否自动删除
+	Internal   bool           `mapstructure:"internal"`    // 是否内部 exchange（不允许客户端直接发布）
+	Args       map[string]any `mapstructure:"args"`        // 额外参数，如 alternate-exchange
+}
+
+// QueueConfig 队列声明配置
+// 常用参数提供了独立字段，其余参数可通过 Args 传入，独立字段优先级高于 Args
+type QueueConfig struct {
+	Name                 string         `mapstructure:"name"`                    // 队列名称
+	Durable              bool           `mapstructure:"durable"`                 // 是否持久化
+	AutoDelete           bool           `mapstructure:"auto_delete"`             // 无消费者时是否自动删除
+	Exclusive            bool           `mapstructure:"exclusive"`               // 是否排他队列
+	QueueType            string         `mapstructure:"queue_type"`              // classic / quorum / stream，默认由服务端决定
+	MessageTTL           time.Duration  `mapstructure:"message_ttl"`             // 消息过期时间（x-message-ttl）
+	Expires              time.Duration  `mapstructure:"expires"`                 // 队列空闲过期时间（x-expires）
+	MaxLength            int64          `mapstructure:"max_length"`              // 最大消息数（x-max-length）
+	MaxLengthBytes       int64          `mapstructure:"max_length_bytes"`        // 最大字节数（x-max-length-bytes）
+	Overflow             string         `mapstructure:"overflow"`                // 超限策略：drop-head / reject-publish / reject-publish-dlx
+	DeadLetterExchange   string         `mapstructure:"dead_letter_exchange"`    // 死信 exchange（x-dead-letter-exchange）
+	DeadLetterRoutingKey string         `mapstructure:"dead_letter_routing_key"` // 死信路由键（x-dead-letter-routing-key）
+	MaxPriority          int            `mapstructure:"max_priority"`            // 最大优先级（x-max-priority）
+	Args                 map[string]any `mapstructure:"args"`                    // 额外参数
+}
+
+// BindingConfig 绑定配置
+// Queue 与 ToExchange 二选一：Queue 为队列绑定，ToExchange 为 exchange 到 exchange 的绑定
+type BindingConfig struct {
+	Exchange   string         `mapstructure:"exchange"`    // 源 exchange
+	Queue      string         `mapstructure:"queue"`       // 目标队列
+	ToExchange string         `mapstructure:"to_exchange"` // 目标 exchange
+	RoutingKey string         `mapstructure:"routing_key"` // 路由键
+	Args       map[string]any `mapstructure:"args"`        // 绑定参数（headers exchange 匹配条件等）
+}
+
+// arguments 将独立字段合并为 amqp 声明参数
+func (q QueueConfig) arguments() amqp.Table {
+	args := amqp.Table{}
+	for k, v := range q.Args {
+		args[k] = tableValue(v)
+	}
+	if q.QueueType != "" {
+		args["x-queue-type"] = q.QueueType
+	}
+	if q.MessageTTL > 0 {
+		args["x-message-ttl"] = q.MessageTTL.Milliseconds()
+	}
+	if q.Expires > 0 {
+		args["x-expires"] = q.Expires.Milliseconds()
+	}
+	if q.MaxLength > 0 {
+		args["x-max-length"] = q.MaxLength
+	}
+	if q.MaxLengthBytes > 0 {
+		args["x-max-length-bytes"] = q.MaxLengthBytes
+	}
+	if q.Overflow != "" {
+		args["x-overflow"] = q.Overflow
+	}
+	if q.DeadLetterExchange != "" {
+		args["x-dead-letter-exchange"] = q.DeadLetterExchange
+	}
+	if q.DeadLetterRoutingKey != "" {
+		args["x-dead-letter-routing-key"] = q.DeadLetterRoutingKey
+	}
+	if q.MaxPriority > 0 {
+		args["x-max-priority"] = q.MaxPriority
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return args
+}
+
+// toTable map 转 amqp.Table，空 map 返回 nil
+// YAML 经 viper/mapstructure 解码后嵌套参数为 map[string]any 或 map[any]any，amqp 无法编码，需递归转换为 amqp.Table
+func toTable(m map[string]any) amqp.Table {
+	if len(m) == 0 {
+		return nil
+	}
+	t := make(amqp.Table, len(m))
+	for k, v := range m {
+		t[k] = tableValue(v)
+	}
+	return t
+}
+
+// tableValue 将嵌套的 map/切片转换为 amqp 可编码的类型
+func tableValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		t := make(amqp.Table, len(v))
+		for k, val := range v {
+			t[k] = tableValue(val)
+		}
+		return t
+	case map[any]any:
+		t := make(amqp.Table, len(v))
+		for k, val := range v {
+			t[fmt.Sprint(k)] = tableValue(val)
+		}
+		return t
+	case []any:
+		s := make([]any, len(v))
+		for i, val := range v {
+			s[i] = tableValue(val)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// Validate 校验拓扑配置
+func (t *Topology) Validate() error {
+	for _, e := range t.Exchanges {
+		if e.Name == "" {
+			return errors.New("mq topology: exchange name is empty")
+		}
+	}
+	for _, q := range t.Queues {
+		if q.Name == "" {
+			return errors.New("mq topology: queue name is empty")
+		}
+	}
+	for _, b := range t.Bindings {
+		if b.Exchange == "" {
+			return errors.New("mq topology: binding exchange is empty")
+		}
+		if (b.Queue == "") == (b.ToExchange == "") {
+			return fmt.Errorf("mq topology: binding on %s must set exactly one of queue/to_exchange", b.Exchange)
+		}
+	}
+	return nil
+}
+
+// merge 合并另一份拓扑，同名 exchange/队列以后者为准，绑定去重
+func (t *Topology) merge(other Topology) {
+	for _, e := range other.Exchanges {
+		replaced := false
+		for i := range t.Exchanges {
+			if t.Exchanges[i].Name == e.Name {
+				t.Exchanges[i] = e
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			t.Exchanges = append(t.Exchanges, e)
+		}
+	}
+	for _, q := range other.Queues {
+		replaced := false
+		for i := range t.Queues {
+			if t.Queues[i].Name == q.Name {
+				t.Queues[i] = q
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			t.Queues = append(t.Queues, q)
+		}
+	}
+	for _, b := range other.Bindings {
+		exists := false
+		for _, old := range t.Bindings {
+			if old.Exchange == b.Exchange && old.Queue == b.Queue &&
+				old.ToExchange == b.ToExchange && old.RoutingKey == b.RoutingKey {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			t.Bindings = append(t.Bindings, b)
+		}
+	}
+}
+
+// declare 在指定通道上依次声明 exchange、队列和绑定（幂等）
+func (t *Topology) declare(ch *amqp.Channel) error {
+	for _, e := range t.Exchanges {
+		typ := e.Type
+		if typ == "" {
+			typ = amqp.ExchangeDirect
+		}
+		if err := ch.ExchangeDeclare(e.Name, typ, e.Durable, e.AutoDelete, e.Internal, false, toTable(e.Args)); err != nil {
+			return fmt.Errorf("declare exchange %s: %w", e.Name, err)
+		}
+	}
+	for _, q := range t.Queues {
+		if _, err := ch.QueueDeclare(q.Name, q.Durable, q.AutoDelete, q.Exclusive, false, q.arguments()); err != nil {
+			return fmt.Errorf("declare queue %s: %w", q.Name, err)
+		}
+	}
+	for _, b := range t.Bindings {
+		var err error
+		if b.Queue != "" {
+			err = ch.QueueBind(b.Queue, b.RoutingKey, b.Exchange, false, toTable(b.Args))
+		} else {
+			err = ch.ExchangeBind(b.ToExchange, b.RoutingKey, b.Exchange, false, toTable(b.Args))
+		}
+		if err != nil {
+			return fmt.Errorf("bind %s -> %s%s: %w", b.Exchange, b.Queue, b.ToExchange, err)
+		}
+	}
+	return nil
+}
+
+// NewRabbitMQClientWithTopology 创建客户端并声明拓扑，断线重连后自动重新声明
+func NewRabbitMQClientWithTopology(url string, topology Topology, logger *zap.Logger) (*RabbitMQClient, error) {
+	client, err := NewRabbitMQClient(url, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.DeclareTopology(topology); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// DeclareTopology 声明拓扑并记录，断线重连后自动重新声明
+// 可多次调用，新拓扑会与已有拓扑合并
+func (c *RabbitMQClient) DeclareTopology(topology Topology) error {
+	if err := topology.Validate(); err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return amqp.ErrClosed
+	}
+	if c.conn == nil || c.conn.IsClosed() {
+		if err := c.connectWithRetry(); err != nil {
+			return err
+		}
+	}
+	ch, err := c.conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+	if err := topology.declare(ch); err != nil {
+		return err
+	}
+	// 声明成功后才记录，失败的拓扑不会在重连时被反复重放
+	c.topology.merge(topology)
+	return nil
+}
+
+// declareTopologyLocked 在当前连接上重新声明已记录的拓扑，调用方需持有 mutex
+func (c *RabbitMQClient) declareTopologyLocked() error {
+	if len(c.topology.Exchanges) == 0 && len(c.topology.Queues) == 0 && len(c.topology.Bindings) == 0 {
+		return nil
+	}
+	ch, err := c.conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+	return c.topology.declare(ch)
+}
+
+// exchangeDeclared 判断 exchange 是否已由客户端预声明，已声明的无需在 Publish/Consume 中重复声明
+func (c *RabbitMQClient) exchangeDeclared(exchange string) bool {
+	if c.predeclareExchange && exchange == c.predeclaredExchange {
+		return true
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, e := range c.topology.Exchanges {
+		if e.Name == exchange {
+			return true
+		}
+	}
+	return false
+}
+
+// queueDeclared 判断队列是否已在拓扑中声明
+func (c *RabbitMQClient) queueDeclared(queue string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, q := range c.topology.Queues {
+		if q.Name == queue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package mq
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/spf13/viper"
+)
+
+const testTopologyYAML = `
+rabbitmq:
+  topology:
+    exchanges:
+      - name: order
+        type: topic
+        durable: true
+      - name: order.dlx
+        type: fanout
+        durable: true
+    queues:
+      - name: order.created
+        durable: true
+        queue_type: quorum
+        message_ttl: 10m
+        max_length: 1000
+        dead_letter_exchange: order.dlx
+        args:
+          x-delivery-limit: 5
+    bindings:
+      - exchange: order
+        queue: order.created
+        routing_key: order.created.#
+`
+
+func TestTopology_LoadFromViper(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(testTopologyYAML)); err != nil {
+		t.Fatalf("读取配置失败: %v", err)
+	}
+	var topo Topology
+	if err := v.UnmarshalKey("rabbitmq.topology", &topo); err != nil {
+		t.Fatalf("解析拓扑失败: %v", err)
+	}
+	if err := topo.Validate(); err != nil {
+		t.Fatalf("拓扑校验失败: %v", err)
+	}
+	if len(topo.Exchanges) != 2 || topo.Exchanges[0].Type != "topic" || !topo.Exchanges[0].Durable {
+		t.Fatalf("exchange 解析不符: %+v", topo.Exchanges)
+	}
+	if len(topo.Queues) != 1 || topo.Queues[0].MessageTTL != 10*time.Minute {
+		t.Fatalf("队列解析不符: %+v", topo.Queues)
+	}
+
+	args := topo.Queues[0].arguments()
+	if args["x-queue-type"] != "quorum" {
+		t.Errorf("x-queue-type 不符: %v", args["x-queue-type"])
+	}
+	if args["x-message-ttl"] != int64(600000) {
+		t.Errorf("x-message-ttl 不符: %v", args["x-message-ttl"])
+	}
+	if args["x-max-length"] != int64(1000) {
+		t.Errorf("x-max-length 不符: %v", args["x-max-length"])
+	}
+	if args["x-dead-letter-exchange"] != "order.dlx" {
+		t.Errorf("x-dead-letter-exchange 不符: %v", args["x-dead-letter-exchange"])
+	}
+	if _, ok := args["x-delivery-limit"]; !ok {
+		t.Error("额外参数 x-delivery-limit 丢失")
+	}
+	if err := args.Validate(); err != nil {
+		t.Errorf("参数无法被 amqp 编码: %v", err)
+	}
+}
+
+func TestTopology_NestedArgsFromViper(t *testing.T) {
+	const yaml = `
+exchanges:
+  - name: events
+    type: headers
+    args:
+      x-meta:
+        owner: order
+        tags: [a, b]
+bindings:
+  - exchange: events
+    queue: events.order
+    args:
+      x-match: all
+      filter:
+        type: order
+        level: 1
+`
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(yaml)); err != nil {
+		t.Fatalf("读取配置失败: %v", err)
+	}
+	var topo Topology
+	if err := v.Unmarshal(&topo); err != nil {
+		t.Fatalf("解析拓扑失败: %v", err)
+	}
+	exArgs := toTable(topo.Exchanges[0].Args)
+	if _, ok := exArgs["x-meta"].(amqp.Table); !ok {
+		t.Fatalf("嵌套参数应转换为 amqp.Table: %T", exArgs["x-meta"])
+	}
+	if err := exArgs.Validate(); err != nil {
+		t.Errorf("exchange 参数无法被 amqp 编码: %v", err)
+	}
+	bindArgs := toTable(topo.Bindings[0].Args)
+	if err := bindArgs.Validate(); err != nil {
+		t.Errorf("绑定参数无法被 amqp 编码: %v", err)
+	}
+	q := QueueConfig{Name: "q", Args: map[string]any{"x-nested": map[any]any{"k": []any{map[string]any{"v": 1}}}}}
+	if err := q.arguments().Validate(); err != nil {
+		t.Errorf("队列参数无法被 amqp 编码: %v", err)
+	}
+}
+
+func TestTopology_ValidateAndMerge(t *testing.T) {
+	bad := Topology{Bindings: []BindingConfig{{Exchange: "a", Queue: "q", ToExchange: "b"}}}
+	if err := bad.Validate(); err == nil {
+		t.Error("同时设置 queue 与 to_exchange 应校验失败")
+	}
+
+	topo := Topology{
+		Exchanges: []ExchangeConfig{{Name: "a", Type: "direct"}},
+		Bindings:  []BindingConfig{{Exchange: "a", Queue: "q", RoutingKey: "k"}},
+	}
+	topo.merge(Topology{
+		Exchanges: []ExchangeConfig{{Name: "a", Type: "topic", Durable: true}, {Name: "b"}},
+		Queues:    []QueueConfig{{Name: "q"}},
+		Bindings:  []BindingConfig{{Exchange: "a", Queue: "q", RoutingKey: "k"}},
+	})
+	if len(topo.Exchanges) != 2 || topo.Exchanges[0].Type != "topic" {
+		t.Errorf("合并 exchange 不符: %+v", topo.Exchanges)
+	}
+	if len(topo.Queues) != 1 || len(topo.Bindings) != 1 {
+		t.Errorf("合并队列/绑定不符: %+v %+v", topo.Queues, topo.Bindings)
+	}
+}
@@ -0,0 +1,207 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// typedValue 带类型的消息头值。普通 JSON 往返后数字统一变为 float64，
+// 而 RabbitMQ 消息头区分 int32/int64/float64 等类型（如 x-delay、x-max-priority），因此按类型存储
+type typedValue struct {
+	Type  string          `json:"t"`
+	Value json.RawMessage `json:"v,omitempty"`
+}
+
+// encodeHeaders 将消息头编码为带类型的 JSON
+func encodeHeaders(headers map[string]any) (string, error) {
+	if len(headers) == 0 {
+		return "", nil
+	}
+	table, err := encodeTable(headers)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(table)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// decodeHeaders 解码消息头，兼容旧版本以普通 JSON 存储的记录
+func decodeHeaders(s string) (map[string]any, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var table map[string]typedValue
+	if err := json.Unmarshal([]byte(s), &table); err == nil {
+		if headers, err := decodeTable(table); err == nil {
+			return headers, nil
+		}
+	}
+	var headers map[string]any
+	if err := json.Unmarshal([]byte(s), &headers); err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
+
+func encodeTable(m map[string]any) (map[string]typedValue, error) {
+	table := make(map[string]typedValue, len(m))
+	for k, v := range m {
+		tv, err := encodeValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %w", k, err)
+		}
+		table[k] = tv
+	}
+	return table, nil
+}
+
+func decodeTable(table map[string]typedValue) (map[string]any, error) {
+	m := make(map[string]any, len(table))
+	for k, tv := range table {
+		v, err := decodeValue(tv)
+		if err != nil {
+			return nil, err
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+func encodeValue(v any) (typedValue, error) {
+	var typ string
+	switch x := v.(type) {
+	case nil:
+		return typedValue{Type: "nil"}, nil
+	case bool:
+		typ = "bool"
+	case string:
+		typ = "string"
+	case []byte:
+		typ = "bytes"
+	case int:
+		typ = "int"
+	case int8:
+		typ = "int8"
+	case int16:
+		typ = "int16"
+	case int32:
+		typ = "int32"
+	case int64:
+		typ = "int64"
+	case uint8:
+		typ = "uint8"
+	case uint16:
+		typ = "uint16"
+	case uint32:
+		typ = "uint32"
+	case float32:
+		typ = "float32"
+	case float64:
+		typ = "float64"
+	case time.Time:
+		typ = "time"
+	case amqp.Decimal:
+		typ = "decimal"
+	case []any:
+		items := make([]typedValue, len(x))
+		for i, item := range x {
+			tv, err := encodeValue(item)
+			if err != nil {
+				return typedValue{}, err
+			}
+			items[i] = tv
+		}
+		v, typ = items, "array"
+	case amqp.Table:
+		table, err := encodeTable(x)
+		if err != nil {
+			return typedValue{}, err
+		}
+		v, typ = table, "table"
+	case map[string]any:
+		table, err := encodeTable(x)
+		if err != nil {
+			return typedValue{}, err
+		}
+		v, typ = table, "table"
+	default:
+		return typedValue{}, fmt.Errorf("unsupported header type %T", v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return typedValue{}, err
+	}
+	return typedValue{Type: typ, Value: data}, nil
+}
+
+func decodeValue(tv typedValue) (any, error) {
+	switch tv.Type {
+	case "nil":
+		return nil, nil
+	case "bool":
+		return decodeAs[bool](tv.Value)
+	case "string":
+		return decodeAs[string](tv.Value)
+	case "bytes":
+		return decodeAs[[]byte](tv.Value)
+	case "int":
+		return decodeAs[int](tv.Value)
+	case "int8":
+		return decodeAs[int8](tv.Value)
+	case "int16":
+		return decodeAs[int16](tv.Value)
+	case "int32":
+		return decodeAs[int32](tv.Value)
+	case "int64":
+		return decodeAs[int64](tv.Value)
+	case "uint8":
+		return decodeAs[uint8](tv.Value)
+	case "uint16":
+		return decodeAs[uint16](tv.Value)
+	case "uint32":
+		return decodeAs[uint32](tv.Value)
+	case "float32":
+		return decodeAs[float32](tv.Value)
+	case "float64":
+		return decodeAs[float64](tv.Value)
+	case "time":
+		return decodeAs[time.Time](tv.Value)
+	case "decimal":
+		return decodeAs[amqp.Decimal](tv.Value)
+	case "array":
+		items, err := decodeAs[[]typedValue](tv.Value)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]any, len(items))
+		for i, item := range items {
+			if values[i], err = decodeValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	case "table":
+		table, err := decodeAs[map[string]typedValue](tv.Value)
+		if err != nil {
+			return nil, err
+		}
+		m, err := decodeTable(table)
+		if err != nil {
+			return nil, err
+		}
+		return amqp.Table(m), nil
+	}
+	return nil, fmt.Errorf("unknown header type %q", tv.Type)
+}
+
+func decodeAs[T any](data json.RawMessage) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
@@ -0,0 +1,55 @@
+package outbox
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestHeaders_RoundTrip(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	headers := map[string]any{
+		"x-delay":   int32(5000),
+		"x-count":   int64(3),
+		"priority":  uint8(5),
+		"ratio":     1.5,
+		"enabled":   true,
+		"trace":     "abc",
+		"raw":       []byte{1, 2},
+		"at":        now,
+		"amount":    amqp.Decimal{Scale: 2, Value: 12345},
+		"empty":     nil,
+		"list":      []any{int16(1), "a"},
+		"x-details": amqp.Table{"retry": int32(2)},
+	}
+	s, err := encodeHeaders(headers)
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	got, err := decodeHeaders(s)
+	if err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	if !reflect.DeepEqual(got, headers) {
+		t.Errorf("消息头类型未保留:\n got=%#v\nwant=%#v", got, headers)
+	}
+
+	if _, err := encodeHeaders(map[string]any{"bad": struct{}{}}); err == nil {
+		t.Error("不支持的类型应返回错误")
+	}
+}
+
+func TestHeaders_Legacy(t *testing.T) {
+	got, err := decodeHeaders(`{"x-delay":5000,"trace":"abc"}`)
+	if err != nil {
+		t.Fatalf("旧格式解码失败: %v", err)
+	}
+	if got["x-delay"] != float64(5000) || got["trace"] != "abc" {
+		t.Errorf("旧格式解码结果不正确: %#v", got)
+	}
+	if got, err := decodeHeaders(""); got != nil || err != nil {
+		t.Errorf("空消息头应返回 nil: %v, %v", got, err)
+	}
+}
@@ -0,0 +1,345 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/muchinfo/mtp2-common-lib/mq"
+	"go.uber.org/zap"
+	"xorm.io/xorm"
+)
+
+// 发件箱记录状态
+const (
+	StatusPending    = 0 // 待发送
+	StatusSent       = 1 // 已发送
+	StatusFailed     = 2 // 重试耗尽，需人工处理
+	StatusProcessing = 3 // 已被中继认领，正在发送
+)
+
+// MaxMessageIDLength 消息 ID 最大长度，对应 id 列宽度
+const MaxMessageIDLength = 64
+
+// ErrMessageIDTooLong 消息 ID 超过 id 列宽度
+var ErrMessageIDTooLong = fmt.Errorf("outbox: message id longer than %d bytes", MaxMessageIDLength)
+
+// Record 发件箱表记录
+type Record struct {
+	Id            string    `xorm:"pk varchar(64) 'id'"`
+	Exchange      string    `xorm:"varchar(255) 'exchange'"`
+	ExchangeType  string    `xorm:"varchar(32) 'exchange_type'"`
+	RoutingKey    string    `xorm:"varchar(255) 'routing_key'"`
+	ContentType   string    `xorm:"varchar(100) 'content_type'"`
+	MessageType   string    `xorm:"varchar(100) 'message_type'"`
+	CorrelationId string    `xorm:"varchar(100) 'correlation_id'"`
+	ReplyTo       string    `xorm:"varchar(255) 'reply_to'"`
+	Persistent    bool      `xorm:"'persistent'"`
+	Priority      int       `xorm:"'priority'"`
+	Expiration    int64     `xorm:"'expiration'"` // 消息过期时间（毫秒），0 表示不过期
+	Headers       string    `xorm:"varchar(4000) 'headers'"`
+	Body          []byte    `xorm:"blob 'body'"`
+	Status        int       `xorm:"index 'status'"`
+	Attempts      int       `xorm:"'attempts'"`
+	NextAttemptAt time.Time `xorm:"index 'next_attempt_at'"`
+	LastError     string    `xorm:"varchar(1000) 'last_error'"`
+	CreatedAt     time.Time `xorm:"'created_at'"`
+	SentAt        time.Time `xorm:"'sent_at'"`
+}
+
+// Config 发件箱配置
+type Config struct {
+	Table          string        // 表名，默认 mq_outbox
+	BatchSize      int           // 每批发送条数，默认 100
+	PollInterval   time.Duration // 轮询间隔，默认 1 秒
+	MaxAttempts    int           // 最大发送次数，超过后标记为失败，默认 10
+	MinBackoff     time.Duration // 首次重试间隔，默认 1 秒
+	MaxBackoff     time.Duration // 最大重试间隔，默认 5 分钟
+	PublishTimeout time.Duration // 单条消息等待确认超时，默认 5 秒
+	ClaimTimeout   time.Duration // 认领后未完成（如进程崩溃）时允许其他中继重新认领的时间，默认 1 分钟，至少为 PublishTimeout 的 2 倍
+}
+
+// Outbox 事务发件箱：业务数据与待发送消息在同一事务中写入数据库，
+// 由后台中继 goroutine 以 publisher confirm 方式发送到 RabbitMQ 并标记已发送，失败按指数退避重试。
+// 多个实例可同时运行中继，每条消息发送前先以条件更新认领，同一时刻只有一个中继发送。
+// 消息至少投递一次，消费端应按消息 ID 去重。
+type Outbox struct {
+	engine    *xorm.Engine
+	publisher mq.ConfirmPublisher
+	config    Config
+	logger    *zap.Logger
+
+	mutex   sync.Mutex
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	running bool
+}
+
+// New 创建发件箱
+// logger 允许为 nil，若为 nil 则不输出日志
+func New(engine *xorm.Engine, publisher mq.ConfirmPublisher, config Config, logger *zap.Logger) *Outbox {
+	if config.Table == "" {
+		config.Table = "mq_outbox"
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 10
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 5 * time.Minute
+	}
+	if config.PublishTimeout <= 0 {
+		config.PublishTimeout = 5 * time.Second
+	}
+	if config.ClaimTimeout <= 0 {
+		config.ClaimTimeout = time.Minute
+	}
+	if config.ClaimTimeout < 2*config.PublishTimeout {
+		config.ClaimTimeout = 2 * config.PublishTimeout
+	}
+	return &Outbox{engine: engine, publisher: publisher, config: config, logger: logger}
+}
+
+// Sync 创建或同步发件箱表结构
+func (o *Outbox) Sync() error {
+	return o.engine.Table(o.config.Table).Sync(new(Record))
+}
+
+// Add 在调用方的事务 session 中写入待发送消息，事务提交后由中继发送
+// session 应已调用 Begin，消息 ID 为空时自动生成，指定的消息 ID 不能超过 MaxMessageIDLength
+func (o *Outbox) Add(session *xorm.Session, exchange, exchangeType, routingKey string, msg *mq.Message) error {
+	if len(msg.ID) > MaxMessageIDLength {
+		return fmt.Errorf("%w: %q", ErrMessageIDTooLong, msg.ID)
+	}
+	if msg.ID == "" || msg.Timestamp.IsZero() {
+		m, err := mq.NewMessage(msg.Body)
+		if err != nil {
+			return err
+		}
+		if msg.ID == "" {
+			msg.ID = m.ID
+		}
+		if msg.Timestamp.IsZero() {
+			msg.Timestamp = m.Timestamp
+		}
+	}
+	headers, err := encodeHeaders(msg.Headers)
+	if err != nil {
+		return fmt.Errorf("marshal outbox headers: %w", err)
+	}
+	rec := &Record{
+		Id:            msg.ID,
+		Exchange:      exchange,
+		ExchangeType:  exchangeType,
+		RoutingKey:    routingKey,
+		ContentType:   msg.ContentType,
+		MessageType:   msg.Type,
+		CorrelationId: msg.CorrelationID,
+		ReplyTo:       msg.ReplyTo,
+		Persistent:    msg.Persistent,
+		Priority:      int(msg.Priority),
+		Expiration:    msg.Expiration.Milliseconds(),
+		Headers:       headers,
+		Body:          msg.Body,
+		Status:        StatusPending,
+		NextAttemptAt: msg.Timestamp,
+		CreatedAt:     msg.Timestamp,
+	}
+	_, err = session.Table(o.config.Table).Insert(rec)
+	return err
+}
+
+// AddJSON 以 JSON 编码 v 并写入发件箱
+func (o *Outbox) AddJSON(session *xorm.Session, exchange, exchangeType, routingKey string, v any, opts ...mq.MessageOption) error {
+	msg, err := mq.EncodeMessage(mq.JSONCodec, v, opts...)
+	if err != nil {
+		return err
+	}
+	return o.Add(session, exchange, exchangeType, routingKey, msg)
+}
+
+// Start 启动中继 goroutine
+func (o *Outbox) Start() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.running {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	o.cancel = cancel
+	o.running = true
+	o.wg.Add(1)
+	go o.relayLoop(ctx)
+}
+
+// Stop 停止中继并等待当前批次结束
+func (o *Outbox) Stop() {
+	o.mutex.Lock()
+	if !o.running {
+		o.mutex.Unlock()
+		return
+	}
+	o.running = false
+	o.cancel()
+	o.mutex.Unlock()
+	o.wg.Wait()
+}
+
+// relayLoop 定时扫描待发送消息，整批发送成功时立即处理下一批
+func (o *Outbox) relayLoop(ctx context.Context) {
+	defer o.wg.Done()
+	ticker := time.NewTicker(o.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := o.RelayOnce(ctx)
+			if err != nil && o.logger != nil && !errors.Is(err, context.Canceled) {
+				o.logger.Error("[Outbox] 扫描发件箱失败", zap.Error(err))
+			}
+			if err != nil || n < o.config.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce 发送一批到期的待发送消息（含认领超时的消息），返回本批扫描条数。
+// 每条消息发送前先认领，已被其他中继认领的消息跳过
+func (o *Outbox) RelayOnce(ctx context.Context) (int, error) {
+	var records []Record
+	err := o.engine.Table(o.config.Table).
+		Where("status IN (?, ?) AND next_attempt_at <= ?", StatusPending, StatusProcessing, time.Now()).
+		OrderBy("id").
+		Limit(o.config.BatchSize).
+		Find(&records)
+	if err != nil {
+		return 0, err
+	}
+	for i := range records {
+		if err := ctx.Err(); err != nil {
+			return i, err
+		}
+		claimed, err := o.claim(&records[i])
+		if err != nil {
+			return i, err
+		}
+		if claimed {
+			o.relay(ctx, &records[i])
+		}
+	}
+	return len(records), nil
+}
+
+// claim 以条件更新认领消息：状态为待发送（或认领已超时）且已到期时改为发送中，并将 next_attempt_at 设为认领超时时间。
+// 并发中继中只有一个能更新成功，返回是否认领成功
+func (o *Outbox) claim(rec *Record) (bool, error) {
+	now := time.Now()
+	claim := &Record{Status: StatusProcessing, NextAttemptAt: now.Add(o.config.ClaimTimeout)}
+	n, err := o.engine.Table(o.config.Table).
+		Where("id = ? AND status IN (?, ?) AND next_attempt_at <= ?", rec.Id, StatusPending, StatusProcessing, now).
+		Cols("status", "next_attempt_at").
+		Update(claim)
+	if err != nil || n != 1 {
+		return false, err
+	}
+	rec.Status, rec.NextAttemptAt = claim.Status, claim.NextAttemptAt
+	return true, nil
+}
+
+// relay 发送单条已认领的消息并更新状态
+func (o *Outbox) relay(ctx context.Context, rec *Record) {
+	msg, err := rec.message()
+	if err == nil {
+		pctx, cancel := context.WithTimeout(ctx, o.config.PublishTimeout)
+		err = o.publisher.PublishMessageWithConfirm(pctx, rec.Exchange, rec.ExchangeType, rec.RoutingKey, msg)
+		cancel()
+	}
+
+	now := time.Now()
+	if err == nil {
+		rec.Status = StatusSent
+		rec.SentAt = now
+		if _, uerr := o.engine.Table(o.config.Table).ID(rec.Id).Cols("status", "sent_at").Update(rec); uerr != nil && o.logger != nil {
+			// 消息已发送但状态未更新，下次会重复发送，由消费端去重
+			o.logger.Error("[Outbox] 更新发送状态失败", zap.String("id", rec.Id), zap.Error(uerr))
+		}
+		return
+	}
+
+	rec.Attempts++
+	rec.LastError = truncate(err.Error(), 1000)
+	rec.NextAttemptAt = now.Add(o.backoff(rec.Attempts))
+	rec.Status = StatusPending
+	if rec.Attempts >= o.config.MaxAttempts {
+		rec.Status = StatusFailed
+	}
+	if o.logger != nil {
+		o.logger.Warn("[Outbox] 消息发送失败",
+			zap.String("id", rec.Id), zap.Int("attempts", rec.Attempts), zap.Bool("failed", rec.Status == StatusFailed), zap.Error(err))
+	}
+	if _, uerr := o.engine.Table(o.config.Table).ID(rec.Id).
+		Cols("status", "attempts", "last_error", "next_attempt_at").Update(rec); uerr != nil && o.logger != nil {
+		o.logger.Error("[Outbox] 更新重试状态失败", zap.String("id", rec.Id), zap.Error(uerr))
+	}
+}
+
+// backoff 第 attempts 次失败后的等待时间：MinBackoff * 2^(attempts-1)，不超过 MaxBackoff
+func (o *Outbox) backoff(attempts int) time.Duration {
+	d := o.config.MinBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= o.config.MaxBackoff {
+			return o.config.MaxBackoff
+		}
+	}
+	return d
+}
+
+// message 由记录还原消息信封，投递属性与写入时一致
+func (rec *Record) message() (*mq.Message, error) {
+	msg := &mq.Message{
+		ID:            rec.Id,
+		Timestamp:     rec.CreatedAt,
+		CorrelationID: rec.CorrelationId,
+		ReplyTo:       rec.ReplyTo,
+		Type:          rec.MessageType,
+		ContentType:   rec.ContentType,
+		Persistent:    rec.Persistent,
+		Priority:      uint8(rec.Priority),
+		Expiration:    time.Duration(rec.Expiration) * time.Millisecond,
+		Body:          rec.Body,
+	}
+	headers, err := decodeHeaders(rec.Headers)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal outbox headers: %w", err)
+	}
+	msg.Headers = headers
+	return msg, nil
+}
+
+// truncate 按字节截断字符串，不截断多字节字符
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
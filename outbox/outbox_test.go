@@ -0,0 +1,244 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muchinfo/mtp2-common-lib/database"
+	"github.com/muchinfo/mtp2-common-lib/mq"
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite"
+	"xorm.io/xorm"
+)
+
+func TestOutbox_Backoff(t *testing.T) {
+	o := New(nil, nil, Config{MinBackoff: time.Second, MaxBackoff: 10 * time.Second}, nil)
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, w := range want {
+		if got := o.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d)=%v want=%v", i+1, got, w)
+		}
+	}
+	if got := truncate("数据库错误", 4); got != "数" {
+		t.Errorf("truncate 截断了多字节字符: %q", got)
+	}
+}
+
+func TestOutbox_AddAndRelay(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	engine, err := database.NewEngine(database.Config{
+		Driver: "sqlite",
+		DSN:    filepath.Join(t.TempDir(), "outbox.db"),
+		Logger: logger,
+	})
+	if err != nil {
+		t.Fatalf("创建 SQLite 引擎失败: %v", err)
+	}
+	defer engine.Close()
+
+	bus := mq.NewMemoryBus(logger)
+	defer bus.Close()
+	if err := bus.DeclareQueue("order", "topic", "order.created", "order.#"); err != nil {
+		t.Fatal(err)
+	}
+
+	box := New(engine, bus, Config{Table: "mq_outbox_test"}, logger)
+	if err := box.Sync(); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+	defer engine.Exec("DROP TABLE mq_outbox_test")
+
+	session := engine.NewSession()
+	defer session.Close()
+	if err := session.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	if err := box.AddJSON(session, "order", "topic", "order.created", map[string]string{"order_id": "A1"}); err != nil {
+		t.Fatalf("写入发件箱失败: %v", err)
+	}
+	if err := session.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := box.RelayOnce(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("中继发送失败: n=%d err=%v", n, err)
+	}
+	if bus.QueueLen("order.created") != 1 {
+		t.Error("消息未投递到队列")
+	}
+	count, err := engine.Table("mq_outbox_test").Where("status = ?", StatusSent).Count()
+	if err != nil || count != 1 {
+		t.Errorf("发送状态未更新: count=%d err=%v", count, err)
+	}
+}
+
+// newTestEngine 创建使用临时 SQLite 数据库的引擎
+func newTestEngine(t *testing.T) *xorm.Engine {
+	t.Helper()
+	engine, err := database.NewEngine(database.Config{
+		Driver: "sqlite",
+		DSN:    filepath.Join(t.TempDir(), "outbox.db"),
+	})
+	if err != nil {
+		t.Fatalf("创建 SQLite 引擎失败: %v", err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	return engine
+}
+
+// newTestOutbox 创建发件箱并建表
+func newTestOutbox(t *testing.T, publisher mq.ConfirmPublisher, config Config) (*Outbox, *xorm.Engine) {
+	t.Helper()
+	engine := newTestEngine(t)
+	box := New(engine, publisher, config, nil)
+	if err := box.Sync(); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+	return box, engine
+}
+
+func addMessage(t *testing.T, box *Outbox, engine *xorm.Engine, routingKey string, msg *mq.Message) {
+	t.Helper()
+	session := engine.NewSession()
+	defer session.Close()
+	if err := session.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	if err := box.Add(session, "order", "topic", routingKey, msg); err != nil {
+		t.Fatalf("写入发件箱失败: %v", err)
+	}
+	if err := session.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOutbox_Claim(t *testing.T) {
+	bus := mq.NewMemoryBus(nil)
+	defer bus.Close()
+	if err := bus.DeclareQueue("order", "topic", "order.created", "order.#"); err != nil {
+		t.Fatal(err)
+	}
+	box, engine := newTestOutbox(t, bus, Config{})
+	other := New(engine, bus, Config{}, nil)
+
+	msg, _ := mq.NewMessage([]byte("a"))
+	addMessage(t, box, engine, "order.created", msg)
+
+	var rec Record
+	if _, err := engine.Table(box.config.Table).ID(msg.ID).Get(&rec); err != nil {
+		t.Fatal(err)
+	}
+	first, second := rec, rec
+	if ok, err := box.claim(&first); !ok || err != nil {
+		t.Fatalf("认领失败: %v, %v", ok, err)
+	}
+	if ok, err := other.claim(&second); ok || err != nil {
+		t.Fatalf("已被认领的消息不应再被其他中继认领: %v, %v", ok, err)
+	}
+	// 另一中继扫描时跳过已认领的消息
+	if _, err := other.RelayOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if bus.QueueLen("order.created") != 0 {
+		t.Error("已认领的消息被重复发送")
+	}
+
+	// 认领超时（如中继崩溃）后可被重新认领并发送
+	if _, err := engine.Exec("UPDATE "+box.config.Table+" SET next_attempt_at = ? WHERE id = ?", time.Now().Add(-time.Second), msg.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := other.RelayOnce(context.Background()); err != nil || n != 1 {
+		t.Fatalf("认领超时的消息应重新发送: n=%d err=%v", n, err)
+	}
+	if bus.QueueLen("order.created") != 1 {
+		t.Error("认领超时的消息未重新发送")
+	}
+}
+
+func TestOutbox_Unroutable(t *testing.T) {
+	bus := mq.NewMemoryBus(nil)
+	defer bus.Close()
+	box, engine := newTestOutbox(t, bus, Config{})
+
+	msg, _ := mq.NewMessage([]byte("a"))
+	addMessage(t, box, engine, "order.created", msg)
+	if _, err := box.RelayOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var rec Record
+	if _, err := engine.Table(box.config.Table).ID(msg.ID).Get(&rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Status != StatusPending || rec.Attempts != 1 || rec.LastError == "" {
+		t.Errorf("无法路由的消息应等待重试而不是标记已发送: %+v", rec)
+	}
+}
+
+// capturePublisher 记录发送的消息，用于检查中继还原的消息属性
+type capturePublisher struct {
+	msgs []*mq.Message
+}
+
+func (p *capturePublisher) PublishMessageWithConfirm(ctx context.Context, exchange, exchangeType, routingKey string, msg *mq.Message) error {
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func TestOutbox_MessageRoundTrip(t *testing.T) {
+	pub := &capturePublisher{}
+	box, engine := newTestOutbox(t, pub, Config{})
+
+	msg, _ := mq.NewMessage([]byte("a"),
+		mq.WithMessageID("order-20261018-000000000000000000000001"),
+		mq.WithCorrelationID("c1"),
+		mq.WithMessageType("order.created"),
+		mq.WithPersistent(),
+		mq.WithPriority(5),
+		mq.WithExpiration(30*time.Second),
+		mq.WithHeader("tenant", "t1"),
+	)
+	msg.ReplyTo = "order.reply"
+	addMessage(t, box, engine, "order.created", msg)
+	if _, err := box.RelayOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(pub.msgs) != 1 {
+		t.Fatalf("应发送 1 条消息: %d", len(pub.msgs))
+	}
+	got := pub.msgs[0]
+	if got.ID != msg.ID || got.CorrelationID != "c1" || got.Type != "order.created" || got.ReplyTo != "order.reply" {
+		t.Errorf("消息属性未还原: %+v", got)
+	}
+	if !got.Persistent || got.Priority != 5 || got.Expiration != 30*time.Second {
+		t.Errorf("投递属性未还原: persistent=%v priority=%d expiration=%v", got.Persistent, got.Priority, got.Expiration)
+	}
+	if got.Header("tenant") != "t1" {
+		t.Errorf("消息头未还原: %v", got.Headers)
+	}
+
+	// 非持久化消息按原样发送
+	transient, _ := mq.NewMessage([]byte("b"))
+	addMessage(t, box, engine, "order.created", transient)
+	if _, err := box.RelayOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(pub.msgs) != 2 || pub.msgs[1].Persistent || pub.msgs[1].Expiration != 0 {
+		t.Errorf("非持久化消息属性不符: %+v", pub.msgs[len(pub.msgs)-1])
+	}
+}
+
+func TestOutbox_MessageIDTooLong(t *testing.T) {
+	box, engine := newTestOutbox(t, nil, Config{})
+	session := engine.NewSession()
+	defer session.Close()
+	msg, _ := mq.NewMessage([]byte("a"), mq.WithMessageID(strings.Repeat("x", MaxMessageIDLength+1)))
+	if err := box.Add(session, "order", "topic", "order.created", msg); !errors.Is(err, ErrMessageIDTooLong) {
+		t.Errorf("超长消息 ID 应返回 ErrMessageIDTooLong: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	mrand "math/rand/v2"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNotFound 数据不存在。loader 返回该错误时结果按 NegativeTTL 缓存，命中时 GetOrLoad 同样返回该错误
+var ErrNotFound = errors.New("redis: not found")
+
+// LoadOptions GetOrLoad 配置
+type LoadOptions struct {
+	Jitter       float64          // TTL 随机抖动比例，默认 0.1（即 ±10%），避免同批写入的键同时过期；负数表示不抖动
+	NegativeTTL  time.Duration    // 不存在结果的缓存时间，默认 1 分钟；负数表示不缓存
+	EarlyRefresh float64          // 提前刷新系数（XFetch 算法的 beta），通常取 1，越大越早刷新；0 表示不提前刷新
+	NotFound     func(error) bool // 判断 loader 错误是否表示数据不存在，默认 errors.Is(err, ErrNotFound)
+}
+
+func (o LoadOptions) withDefaults() LoadOptions {
+	if o.Jitter == 0 {
+		o.Jitter = 0.1
+	}
+	if o.NegativeTTL == 0 {
+		o.NegativeTTL = time.Minute
+	}
+	if o.NotFound == nil {
+		o.NotFound = func(err error) bool { return errors.Is(err, ErrNotFound) }
+	}
+	return o
+}
+
+// cacheEntry 缓存在 Redis 中的值及加载元数据
+type cacheEntry struct {
+	Value    json.RawMessage `json:"v,omitempty"`
+	NotFound bool            `json:"nf,omitempty"`
+	Delta    int64           `json:"d,omitempty"` // 加载耗时（毫秒）
+	Expires  int64           `json:"e"`           // 过期时间（Unix 毫秒）
+}
+
+// shouldRefresh XFetch：越接近过期、加载越慢，提前刷新的概率越大
+func (e *cacheEntry) shouldRefresh(beta float64) bool {
+	if beta <= 0 || e.NotFound {
+		return false
+	}
+	gap := -float64(e.Delta) * beta * math.Log(1-mrand.Float64())
+	return float64(time.Now().UnixMilli())+gap >= float64(e.Expires)
+}
+
+// GetOrLoad 按默认配置读取缓存，未命中时调用 loader 加载并写入缓存，详见 GetOrLoadOptions
+func GetOrLoad[T any](r *RedisClient, key string, ttl time.Duration, loader func(ctx context.Context) (T, error)) (T, error) {
+	return GetOrLoadOptions(r, key, ttl, loader, LoadOptions{})
+}
+
+// GetOrLoadOptions 读取缓存（cache-aside），未命中时调用 loader 加载并以 JSON 写入缓存。
+//   - 进程内同一键的并发未命中只执行一次 loader，等待方随客户端 context 取消而返回
+//   - loader 使用不随请求取消的 context，避免发起请求被取消时其他等待方一起失败
+//   - Redis 读写失败时降级为直接加载，错误通过 SetCallbacks 的 onError 回调上报
+//
+// 缓存值带有加载元数据，不能与 GetJSON 混用；失效时直接 Del 对应键
+func GetOrLoadOptions[T any](r *RedisClient, key string, ttl time.Duration, loader func(ctx context.Context) (T, error), opts LoadOptions) (T, error) {
+	opts = opts.withDefaults()
+	var zero T
+
+	data, err := r.client.Get(r.ctx, key).Bytes()
+	if err != nil && err != redis.Nil {
+		r.handleError("GetOrLoad", err)
+	}
+	if err == nil {
+		if entry, value, err := decodeCacheEntry[T](data); err == nil {
+			if entry.shouldRefresh(opts.EarlyRefresh) {
+				go r.flight.Do(key, func() (any, error) {
+					return loadCacheEntry(r, key, ttl, loader, opts)
+				})
+			}
+			return value, entryErr(entry)
+		}
+	}
+
+	ch := r.flight.DoChan(key, func() (any, error) {
+		return loadCacheEntry(r, key, ttl, loader, opts)
+	})
+	select {
+	case <-r.ctx.Done():
+		return zero, r.ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		// 各调用方分别解码，避免共享同一个可变值
+		entry, value, err := decodeCacheEntry[T](res.Val.([]byte))
+		if err != nil {
+			return zero, err
+		}
+		return value, entryErr(entry)
+	}
+}
+
+// loadCacheEntry 调用 loader 并写入缓存，返回编码后的缓存值
+func loadCacheEntry[T any](r *RedisClient, key string, ttl time.Duration, loader func(ctx context.Context) (T, error), opts LoadOptions) ([]byte, error) {
+	ctx := context.WithoutCancel(r.ctx)
+	start := time.Now()
+	value, err := loader(ctx)
+	entry := cacheEntry{Delta: time.Since(start).Milliseconds()}
+	switch {
+	case err == nil:
+		if entry.Value, err = json.Marshal(value); err != nil {
+			return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+	case opts.NotFound(err):
+		entry.NotFound, ttl = true, opts.NegativeTTL
+	default:
+		return nil, err
+	}
+
+	if opts.Jitter > 0 {
+		ttl += time.Duration(float64(ttl) * opts.Jitter * (2*mrand.Float64() - 1))
+	}
+	entry.Expires = time.Now().Add(ttl).UnixMilli()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	if ttl > 0 {
+		// 写缓存失败不影响本次返回
+		if err := r.client.Set(ctx, key, data, ttl).Err(); err != nil {
+			r.handleError("GetOrLoad", err)
+		}
+	}
+	return data, nil
+}
+
+func decodeCacheEntry[T any](data []byte) (*cacheEntry, T, error) {
+	var (
+		entry cacheEntry
+		value T
+	)
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, value, fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+	if !entry.NotFound {
+		if err := json.Unmarshal(entry.Value, &value); err != nil {
+			return nil, value, fmt.Errorf("failed to unmarshal JSON: %w", err)
+		}
+	}
+	return &entry, value, nil
+}
+
+func entryErr(entry *cacheEntry) error {
+	if entry.NotFound {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// unreachableClient 连接不可用的客户端，用于验证缓存不可用时的降级行为
+func unreachableClient() *RedisClient {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RedisClient{
+		client: redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}),
+		ctx:    ctx,
+		cancel: cancel,
+		flight: new(singleflight.Group),
+	}
+}
+
+func TestGetOrLoad_Singleflight(t *testing.T) {
+	client := unreachableClient()
+	defer client.Close()
+
+	var calls atomic.Int32
+	loader := func(ctx context.Context) (TestUser, error) {
+		calls.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		return TestUser{ID: 1, Name: "Alice"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := GetOrLoad(client, "test:cache:user:1", time.Minute, loader)
+			if err != nil || user.Name != "Alice" {
+				t.Errorf("GetOrLoad failed: %+v, %v", user, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("concurrent misses should load once, got %d", calls.Load())
+	}
+}
+
+func TestGetOrLoad_Errors(t *testing.T) {
+	client := unreachableClient()
+	defer client.Close()
+
+	_, err := GetOrLoad(client, "test:cache:missing", time.Minute, func(ctx context.Context) (*TestUser, error) {
+		return nil, ErrNotFound
+	})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	errMissing := errors.New("record not found")
+	_, err = GetOrLoadOptions(client, "test:cache:missing", time.Minute, func(ctx context.Context) (*TestUser, error) {
+		return nil, errMissing
+	}, LoadOptions{NotFound: func(err error) bool { return errors.Is(err, errMissing) }})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("custom NotFound should map to ErrNotFound, got %v", err)
+	}
+
+	errDB := errors.New("db down")
+	if _, err = GetOrLoad(client, "test:cache:error", time.Minute, func(ctx context.Context) (int, error) {
+		return 0, errDB
+	}); !errors.Is(err, errDB) {
+		t.Errorf("loader error should be returned, got %v", err)
+	}
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = GetOrLoad(client.WithContext(reqCtx), "test:cache:slow", time.Minute, func(ctx context.Context) (int, error) {
+		if ctx.Err() != nil {
+			t.Error("loader context should not be canceled with the request")
+		}
+		time.Sleep(50 * time.Millisecond)
+		return 1, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("waiting should honor the client context, got %v", err)
+	}
+}
+
+func TestCacheEntry_ShouldRefresh(t *testing.T) {
+	now := time.Now()
+	fresh := &cacheEntry{Delta: 10, Expires: now.Add(time.Hour).UnixMilli()}
+	expiring := &cacheEntry{Delta: 10, Expires: now.Add(-time.Millisecond).UnixMilli()}
+	for range 100 {
+		if fresh.shouldRefresh(1) {
+			t.Fatal("fresh entry should not be refreshed")
+		}
+		if !expiring.shouldRefresh(1) {
+			t.Fatal("expiring entry should be refreshed")
+		}
+	}
+	if expiring.shouldRefresh(0) {
+		t.Error("early refresh should be disabled when beta is 0")
+	}
+	if (&cacheEntry{NotFound: true}).shouldRefresh(1) {
+		t.Error("negative entries should not be refreshed early")
+	}
+
+	// 距过期 1 秒、加载耗时 1 秒时，刷新概率约为 1/e
+	near := &cacheEntry{Delta: 1000, Expires: now.Add(time.Second).UnixMilli()}
+	refreshed := 0
+	for range 1000 {
+		if near.shouldRefresh(1) {
+			refreshed++
+		}
+	}
+	if refreshed < 250 || refreshed > 500 {
+		t.Errorf("unexpected refresh rate: %d/1000", refreshed)
+	}
+}
+
+func TestGetOrLoad_Cache(t *testing.T) {
+	client, err := getTestRedisClient()
+	if err != nil {
+		t.Skipf("Redis server not available: %v", err)
+		return
+	}
+	defer client.Close()
+	client.Del("test:cache:hit", "test:cache:negative")
+
+	var calls atomic.Int32
+	loader := func(ctx context.Context) (TestUser, error) {
+		calls.Add(1)
+		return TestUser{ID: 2, Name: "Bob"}, nil
+	}
+	for range 3 {
+		user, err := GetOrLoad(client, "test:cache:hit", time.Minute, loader)
+		if err != nil || user.ID != 2 {
+			t.Fatalf("GetOrLoad failed: %+v, %v", user, err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("cached value should be reused, loader called %d times", calls.Load())
+	}
+	if ttl, _ := client.TTL("test:cache:hit"); ttl < 54*time.Second || ttl > 66*time.Second {
+		t.Errorf("TTL should be within jitter range, got %v", ttl)
+	}
+
+	var negative atomic.Int32
+	for range 3 {
+		_, err := GetOrLoad(client, "test:cache:negative", time.Minute, func(ctx context.Context) (TestUser, error) {
+			negative.Add(1)
+			return TestUser{}, ErrNotFound
+		})
+		if !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	}
+	if negative.Load() != 1 {
+		t.Errorf("not-found result should be cached, loader called %d times", negative.Load())
+	}
+	client.Del("test:cache:hit", "test:cache:negative")
+}
@@ -7,23 +7,39 @@ import (
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
 )
 
-// RedisClient Redis客户端结构体
+// Redis 部署模式
+const (
+	ModeSingle   = "single"   // 单节点
+	ModeSentinel = "sentinel" // 哨兵（主从自动故障转移）
+	ModeCluster  = "cluster"  // 集群
+)
+
+// RedisClient Redis客户端结构体，单节点、哨兵、集群模式共用同一套 API
 type RedisClient struct {
-	client      *redis.Client
+	client      redis.UniversalClient
 	config      RedisConfig
 	ctx         context.Context
 	cancel      context.CancelFunc
 	onError     func(error)
 	onReconnect func()
+	view        bool                // 由 WithContext 创建的视图
+	flight      *singleflight.Group // GetOrLoad 的进程内合并加载，视图间共享
 }
 
 // RedisConfig Redis配置结构体
 type RedisConfig struct {
-	Address            string        // Redis服务器地址，格式：host:port
+	Mode               string        // 部署模式：single / sentinel / cluster，为空时自动判断：设置 MasterName 为哨兵，Addresses 多于一个为集群
+	Address            string        // Redis服务器地址，格式：host:port（单节点模式）
+	Addresses          []string      // 哨兵地址或集群节点地址列表
+	MasterName         string        // 哨兵模式的主节点名称
+	Username           string        // ACL 用户名（Redis 6+），为空时仅使用密码认证
 	Password           string        // 密码
-	Database           int           // 数据库编号，默认0
+	SentinelPassword   string        // 哨兵节点密码，为空时不认证
+	Database           int           // 数据库编号，默认0；集群模式只能为0
+	ReadOnly           bool          // 集群模式下允许从副本节点读取
 	PoolSize           int           // 连接池大小，默认10
 	MinIdleConns       int           // 最小空闲连接数，默认0
 	MaxConnAge         time.Duration // 连接最大存活时间，默认0（永不过期）
@@ -41,8 +57,9 @@ type RedisConfig struct {
 // NewRedisClient 创建新的Redis客户端
 func NewRedisClient(config RedisConfig) (*RedisClient, error) {
 	// 设置默认值
-	if config.Address == "" {
-		config.Address = "localhost:6379"
+	mode, err := resolveMode(&config)
+	if err != nil {
+		return nil, err
 	}
 	if config.PoolSize == 0 {
 		config.PoolSize = 10
@@ -78,47 +95,119 @@ func NewRedisClient(config RedisConfig) (*RedisClient, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// 创建Redis客户端选项
-	opts := &redis.Options{
-		Addr:            config.Address,
-		Password:        config.Password,
-		DB:              config.Database,
-		PoolSize:        config.PoolSize,
-		MinIdleConns:    config.MinIdleConns,
-		ConnMaxLifetime: 1 * time.Hour, // 替代 MaxConnAge
-		PoolTimeout:     config.PoolTimeout,
-		ConnMaxIdleTime: config.IdleTimeout, // 替代 IdleTimeout
-		DialTimeout:     config.DialTimeout,
-		ReadTimeout:     config.ReadTimeout,
-		WriteTimeout:    config.WriteTimeout,
-		MaxRetries:      config.MaxRetries,
-	}
-
-	client := redis.NewClient(opts)
+	opts := &redis.UniversalOptions{
+		Addrs:            config.Addresses,
+		MasterName:       config.MasterName,
+		Username:         config.Username,
+		Password:         config.Password,
+		SentinelPassword: config.SentinelPassword,
+		DB:               config.Database,
+		ReadOnly:         config.ReadOnly,
+		PoolSize:         config.PoolSize,
+		MinIdleConns:     config.MinIdleConns,
+		ConnMaxLifetime:  1 * time.Hour, // 替代 MaxConnAge
+		PoolTimeout:      config.PoolTimeout,
+		ConnMaxIdleTime:  config.IdleTimeout, // 替代 IdleTimeout
+		DialTimeout:      config.DialTimeout,
+		ReadTimeout:      config.ReadTimeout,
+		WriteTimeout:     config.WriteTimeout,
+		MaxRetries:       config.MaxRetries,
+	}
+
+	var client redis.UniversalClient
+	switch mode {
+	case ModeCluster:
+		client = redis.NewClusterClient(opts.Cluster())
+	case ModeSentinel:
+		client = redis.NewFailoverClient(opts.Failover())
+	default:
+		opts.Addrs = []string{config.Address}
+		client = redis.NewClient(opts.Simple())
+	}
 
 	redisClient := &RedisClient{
 		client: client,
 		config: config,
 		ctx:    ctx,
 		cancel: cancel,
+		flight: new(singleflight.Group),
 	}
 
 	// 测试连接
 	if err := redisClient.Ping(); err != nil {
 		cancel()
+		client.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
 	return redisClient, nil
 }
 
+// resolveMode 确定部署模式并校验、补全地址配置
+func resolveMode(config *RedisConfig) (string, error) {
+	mode := config.Mode
+	if mode == "" {
+		switch {
+		case config.MasterName != "":
+			mode = ModeSentinel
+		case len(config.Addresses) > 1:
+			mode = ModeCluster
+		default:
+			mode = ModeSingle
+		}
+	}
+
+	switch mode {
+	case ModeSingle:
+		if config.Address == "" && len(config.Addresses) > 0 {
+			config.Address = config.Addresses[0]
+		}
+		if config.Address == "" {
+			config.Address = "localhost:6379"
+		}
+	case ModeSentinel, ModeCluster:
+		if len(config.Addresses) == 0 && config.Address != "" {
+			config.Addresses = []string{config.Address}
+		}
+		if len(config.Addresses) == 0 {
+			return "", fmt.Errorf("redis %s mode requires Addresses", mode)
+		}
+		if mode == ModeSentinel && config.MasterName == "" {
+			return "", fmt.Errorf("redis sentinel mode requires MasterName")
+		}
+		if mode == ModeCluster && config.Database != 0 {
+			return "", fmt.Errorf("redis cluster mode only supports database 0")
+		}
+	default:
+		return "", fmt.Errorf("unknown redis mode: %s", mode)
+	}
+	config.Mode = mode
+	return mode, nil
+}
+
 // SetCallbacks 设置回调函数
 func (r *RedisClient) SetCallbacks(onError func(error), onReconnect func()) {
 	r.onError = onError
 	r.onReconnect = onReconnect
 }
 
+// WithContext 返回使用 ctx 的客户端视图：全部操作遵守 ctx 的超时与取消，并将其中的链路信息传给 go-redis hook。
+// 视图与原客户端共享连接池和回调，创建开销很小，可按请求创建；视图的 Close 为空操作
+func (r *RedisClient) WithContext(ctx context.Context) *RedisClient {
+	if ctx == nil {
+		panic("redis: nil context")
+	}
+	view := *r
+	view.ctx = ctx
+	view.view = true
+	return &view
+}
+
 // Close 关闭Redis客户端
 func (r *RedisClient) Close() error {
+	if r.view {
+		return nil
+	}
 	r.cancel()
 	return r.client.Close()
 }
@@ -128,12 +217,24 @@ func (r *RedisClient) Ping() error {
 	return r.client.Ping(r.ctx).Err()
 }
 
-// GetClient 获取原始Redis客户端（用于高级操作）
+// GetClient 获取原始Redis客户端（用于高级操作）。单节点与哨兵模式返回 *redis.Client，集群模式返回 nil，
+// 需要兼容各模式时使用 GetUniversalClient
 func (r *RedisClient) GetClient() *redis.Client {
+	client, _ := r.client.(*redis.Client)
+	return client
+}
+
+// GetUniversalClient 获取原始客户端接口，按部署模式为 *redis.Client 或 *redis.ClusterClient
+func (r *RedisClient) GetUniversalClient() redis.UniversalClient {
 	return r.client
 }
 
-// GetContext 获取上下文
+// Mode 部署模式：single / sentinel / cluster
+func (r *RedisClient) Mode() string {
+	return r.config.Mode
+}
+
+// GetContext 获取上下文，WithContext 视图返回传入的 ctx
 func (r *RedisClient) GetContext() context.Context {
 	return r.ctx
 }
@@ -174,6 +275,12 @@ func (r *RedisClient) GetSet(key string, value interface{}) (string, error) {
 	return val, r.handleError("GetSet", err)
 }
 
+// SetNX 键不存在时设置值，返回是否设置成功
+func (r *RedisClient) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	ok, err := r.client.SetNX(r.ctx, key, value, expiration).Result()
+	return ok, r.handleError("SetNX", err)
+}
+
 // Incr 自增1
 func (r *RedisClient) Incr(key string) (int64, error) {
 	val, err := r.client.Incr(r.ctx, key).Result()
@@ -1,6 +1,8 @@
 package redis
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -118,8 +120,18 @@ func TestRedisClient_StringOperations(t *testing.T) {
 		t.Errorf("Expected 2 values, got %d", len(values))
 	}
 
+	// 测试SetNX
+	ok, err := client.SetNX("test:setnx", "first", time.Minute)
+	if err != nil || !ok {
+		t.Errorf("SetNX on new key failed: ok=%v err=%v", ok, err)
+	}
+	ok, err = client.SetNX("test:setnx", "second", time.Minute)
+	if err != nil || ok {
+		t.Errorf("SetNX on existing key should fail: ok=%v err=%v", ok, err)
+	}
+
 	// 清理测试数据
-	client.Del("test:string", "test:string2", "test:string3", "test:counter")
+	client.Del("test:string", "test:string2", "test:string3", "test:counter", "test:setnx")
 }
 
 func TestRedisClient_HashOperations(t *testing.T) {
@@ -766,3 +778,86 @@ func BenchmarkRedisClient_SetJSON(b *testing.B) {
 		}
 	}
 }
+
+func TestResolveMode(t *testing.T) {
+	cases := []struct {
+		config  RedisConfig
+		mode    string
+		wantErr bool
+	}{
+		{RedisConfig{}, ModeSingle, false},
+		{RedisConfig{Addresses: []string{"10.0.0.1:6379"}}, ModeSingle, false},
+		{RedisConfig{MasterName: "mymaster", Addresses: []string{"10.0.0.1:26379", "10.0.0.2:26379"}}, ModeSentinel, false},
+		{RedisConfig{Addresses: []string{"10.0.0.1:7000", "10.0.0.2:7000"}}, ModeCluster, false},
+		{RedisConfig{Mode: ModeCluster, Address: "10.0.0.1:7000"}, ModeCluster, false},
+		{RedisConfig{Mode: ModeSentinel, Addresses: []string{"10.0.0.1:26379"}}, "", true},
+		{RedisConfig{Addresses: []string{"a:7000", "b:7000"}, Database: 1}, "", true},
+		{RedisConfig{Mode: "unknown"}, "", true},
+	}
+	for i, c := range cases {
+		config := c.config
+		mode, err := resolveMode(&config)
+		if (err != nil) != c.wantErr || mode != c.mode {
+			t.Errorf("case %d: mode=%q err=%v, want mode=%q wantErr=%v", i, mode, err, c.mode, c.wantErr)
+		}
+	}
+
+	config := RedisConfig{Addresses: []string{"10.0.0.1:6379"}}
+	resolveMode(&config)
+	if config.Address != "10.0.0.1:6379" {
+		t.Errorf("single mode should use the first address, got %q", config.Address)
+	}
+	config = RedisConfig{Mode: ModeCluster, Address: "10.0.0.1:7000"}
+	resolveMode(&config)
+	if len(config.Addresses) != 1 || config.Addresses[0] != "10.0.0.1:7000" {
+		t.Errorf("cluster mode should fall back to Address, got %v", config.Addresses)
+	}
+}
+
+func TestRedisClient_GetClient(t *testing.T) {
+	single := &RedisClient{client: redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})}
+	defer single.client.Close()
+	if single.GetClient() == nil || single.GetUniversalClient() != single.GetClient() {
+		t.Error("single mode should expose *redis.Client")
+	}
+
+	cluster := &RedisClient{client: redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{"127.0.0.1:1"}})}
+	defer cluster.client.Close()
+	if cluster.GetClient() != nil {
+		t.Error("cluster mode should return nil from GetClient")
+	}
+	if _, ok := cluster.GetUniversalClient().(*redis.ClusterClient); !ok {
+		t.Error("GetUniversalClient should return the cluster client")
+	}
+}
+
+func TestRedisClient_WithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	base := &RedisClient{
+		client: redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	defer base.Close()
+
+	reqCtx, reqCancel := context.WithCancel(context.Background())
+	reqCancel()
+	view := base.WithContext(reqCtx)
+	if view.GetContext() != reqCtx {
+		t.Error("view should use the request context")
+	}
+	if _, err := view.Get("test:ctx"); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled request context should abort the call, got %v", err)
+	}
+
+	deadline, cancelDeadline := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancelDeadline()
+	time.Sleep(time.Millisecond)
+	if err := base.WithContext(deadline).Set("test:ctx", "v", time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expired deadline should abort the call, got %v", err)
+	}
+
+	if err := view.Close(); err != nil || base.GetContext().Err() != nil {
+		t.Error("closing a view must not close the underlying client")
+	}
+}
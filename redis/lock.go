@@ -0,0 +1,268 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mrand "math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrLockNotObtained = errors.New("redis: lock not obtained") // 等待加锁时 context 结束
+	ErrLockNotHeld     = errors.New("redis: lock not held")     // 未持有锁或锁已过期被他人获取
+)
+
+// 锁以哈希存储：field 为持有者 token，value 为重入次数，整体设置过期时间
+var (
+	// KEYS[1] 锁键；ARGV[1] token；ARGV[2] 租约毫秒；ARGV[3] 是否可重入。成功返回重入次数，失败返回 0
+	lockAcquireScript = redis.NewScript(`
+if redis.call('exists', KEYS[1]) == 0 then
+	redis.call('hset', KEYS[1], ARGV[1], 1)
+	redis.call('pexpire', KEYS[1], ARGV[2])
+	return 1
+end
+if ARGV[3] == '1' and redis.call('hexists', KEYS[1], ARGV[1]) == 1 then
+	local n = redis.call('hincrby', KEYS[1], ARGV[1], 1)
+	redis.call('pexpire', KEYS[1], ARGV[2])
+	return n
+end
+return 0`)
+
+	// 返回剩余重入次数，0 表示已删除锁，-1 表示不是持有者
+	lockReleaseScript = redis.NewScript(`
+if redis.call('hexists', KEYS[1], ARGV[1]) == 0 then
+	return -1
+end
+local n = redis.call('hincrby', KEYS[1], ARGV[1], -1)
+if n > 0 then
+	redis.call('pexpire', KEYS[1], ARGV[2])
+	return n
+end
+redis.call('del', KEYS[1])
+return 0`)
+
+	// 仍是持有者时续期并返回 1，否则返回 0
+	lockRenewScript = redis.NewScript(`
+if redis.call('hexists', KEYS[1], ARGV[1]) == 1 then
+	return redis.call('pexpire', KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// LockOptions 分布式锁配置
+type LockOptions struct {
+	TTL           time.Duration // 租约时长，默认 30 秒；持有期间由看门狗自动续期，进程崩溃后锁在租约到期时释放
+	RenewInterval time.Duration // 看门狗续期间隔，默认（或不小于 TTL 时）为 TTL/3；负数表示不自动续期
+	RetryInterval time.Duration // Lock 等待时的重试间隔，默认 100 毫秒，实际间隔附加随机抖动
+	Reentrant     bool          // 可重入：持有者重复加锁时计数加一，Unlock 相同次数后释放
+	Token         string        // 持有者标识，默认随机生成；可重入时 Token 相同的锁视为同一持有者
+}
+
+// Lock 基于 Redis 的分布式互斥锁，用于多节点间的定时任务互斥等场景。
+// 加锁与释放使用 Lua 脚本原子执行，只有持有者能释放；同一 Lock 可在多个 goroutine 间共享
+type Lock struct {
+	client  *RedisClient
+	key     string
+	token   string
+	options LockOptions
+
+	mutex sync.Mutex
+	count int           // 本地持有（重入）次数
+	lost  chan struct{} // 本次持有期间锁丢失时关闭
+	stop  chan struct{} // 关闭时看门狗退出
+}
+
+// NewLock 创建分布式锁。Lock 的等待受客户端 context 控制，需要超时时在 WithContext 视图上创建
+func (r *RedisClient) NewLock(key string, options LockOptions) *Lock {
+	if options.TTL <= 0 {
+		options.TTL = 30 * time.Second
+	}
+	if options.RenewInterval == 0 || options.RenewInterval >= options.TTL {
+		options.RenewInterval = options.TTL / 3
+	}
+	if options.RetryInterval <= 0 {
+		options.RetryInterval = 100 * time.Millisecond
+	}
+	token := options.Token
+	if token == "" {
+		token = newToken()
+	}
+	return &Lock{
+		client:  r,
+		key:     key,
+		token:   token,
+		options: options,
+		lost:    make(chan struct{}),
+	}
+}
+
+// Key 锁键
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Token 持有者标识
+func (l *Lock) Token() string {
+	return l.token
+}
+
+// TryLock 尝试加锁一次，锁被他人持有时立即返回 false。
+// 不可重入的锁被自身持有时同样返回 false
+func (l *Lock) TryLock() (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	reentrant := "0"
+	if l.options.Reentrant {
+		reentrant = "1"
+	}
+	n, err := lockAcquireScript.Run(l.client.ctx, l.client.client, []string{l.key},
+		l.token, l.options.TTL.Milliseconds(), reentrant).Int64()
+	if err != nil {
+		return false, l.client.handleError("TryLock", err)
+	}
+	if n == 0 {
+		return false, nil
+	}
+	if l.count == 0 {
+		l.lost = make(chan struct{})
+		if l.options.RenewInterval > 0 {
+			l.stop = make(chan struct{})
+			go l.watchdog(l.stop, l.lost)
+		}
+	}
+	l.count++
+	return true, nil
+}
+
+// Lock 阻塞加锁，直到成功或客户端 context 结束（返回 ErrLockNotObtained）
+func (l *Lock) Lock() error {
+	ctx := l.client.ctx
+	for {
+		ok, err := l.TryLock()
+		if err != nil || ok {
+			return err
+		}
+		timer := time.NewTimer(l.retryDelay())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %w", ErrLockNotObtained, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// Unlock 释放锁，可重入时计数减一，减到 0 才真正释放。
+// 锁已过期或被他人持有时返回 ErrLockNotHeld；释放失败时停止续期，锁在租约到期后自动释放。
+// 请求 context 已取消时仍会执行释放，可放心 defer
+func (l *Lock) Unlock() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.count == 0 {
+		return ErrLockNotHeld
+	}
+
+	ctx := context.WithoutCancel(l.client.ctx)
+	n, err := lockReleaseScript.Run(ctx, l.client.client, []string{l.key},
+		l.token, l.options.TTL.Milliseconds()).Int64()
+	switch {
+	case err != nil:
+		l.reset()
+		return l.client.handleError("Unlock", err)
+	case n < 0:
+		close(l.lost)
+		l.reset()
+		return ErrLockNotHeld
+	}
+	l.count--
+	if l.count == 0 {
+		l.reset()
+	}
+	return nil
+}
+
+// Held 本地是否持有锁（未释放且未检测到丢失）
+func (l *Lock) Held() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.count > 0
+}
+
+// Lost 返回本次持有期间锁丢失（续期失败直至租约到期，或锁已被删除、被他人获取）时关闭的 channel。
+// 正常 Unlock 不会关闭该 channel，任务应同时监听自身的结束信号
+func (l *Lock) Lost() <-chan struct{} {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.lost
+}
+
+// reset 清除本地持有状态并停止看门狗，调用方需持有 mutex
+func (l *Lock) reset() {
+	l.count = 0
+	if l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
+}
+
+// watchdog 持有期间定期续期；确认不再是持有者，或续期持续失败超过租约时长时判定锁丢失
+func (l *Lock) watchdog(stop, lost chan struct{}) {
+	ticker := time.NewTicker(l.options.RenewInterval)
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		// 续期不受请求 context 取消影响，只随 Unlock 停止
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(l.client.ctx), l.options.RenewInterval)
+		n, err := lockRenewScript.Run(ctx, l.client.client, []string{l.key},
+			l.token, l.options.TTL.Milliseconds()).Int64()
+		cancel()
+		if err != nil {
+			l.client.handleError("LockRenew", err)
+			if time.Since(renewed) < l.options.TTL {
+				continue
+			}
+		} else if n == 1 {
+			renewed = time.Now()
+			continue
+		}
+		l.markLost(lost)
+		return
+	}
+}
+
+// markLost 锁丢失时关闭 lost 并清除本地状态；锁已释放或重新获取时忽略
+func (l *Lock) markLost(lost chan struct{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.lost != lost || l.count == 0 {
+		return
+	}
+	close(lost)
+	l.reset()
+}
+
+// retryDelay 重试间隔附加 ±50% 随机抖动，避免多个节点同时争抢
+func (l *Lock) retryDelay() time.Duration {
+	interval := l.options.RetryInterval
+	return interval/2 + mrand.N(interval)
+}
+
+// newToken 生成 32 位十六进制随机标识
+func newToken() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
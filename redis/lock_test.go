@@ -0,0 +1,198 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLock_Options(t *testing.T) {
+	client := &RedisClient{}
+	lock := client.NewLock("test:lock", LockOptions{})
+	if lock.options.TTL != 30*time.Second || lock.options.RenewInterval != 10*time.Second || lock.options.RetryInterval != 100*time.Millisecond {
+		t.Errorf("unexpected defaults: %+v", lock.options)
+	}
+	if len(lock.Token()) != 32 || lock.Token() == client.NewLock("test:lock", LockOptions{}).Token() {
+		t.Errorf("token should be unique, got %q", lock.Token())
+	}
+
+	lock = client.NewLock("test:lock", LockOptions{TTL: time.Second, RenewInterval: 2 * time.Second, Token: "job-1"})
+	if lock.options.RenewInterval != time.Second/3 || lock.Token() != "job-1" {
+		t.Errorf("renew interval should be clamped below TTL: %+v", lock.options)
+	}
+	if lock = client.NewLock("test:lock", LockOptions{RenewInterval: -1}); lock.options.RenewInterval >= 0 {
+		t.Error("negative renew interval should disable the watchdog")
+	}
+
+	for range 100 {
+		if d := lock.retryDelay(); d < 50*time.Millisecond || d >= 150*time.Millisecond {
+			t.Fatalf("retry delay out of range: %v", d)
+		}
+	}
+}
+
+func TestLock_Unavailable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	base := &RedisClient{
+		client: redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	defer base.Close()
+
+	lock := base.NewLock("test:lock", LockOptions{})
+	if err := lock.Unlock(); !errors.Is(err, ErrLockNotHeld) {
+		t.Errorf("unlock without holding should fail, got %v", err)
+	}
+	if ok, err := lock.TryLock(); ok || err == nil {
+		t.Errorf("TryLock should fail when Redis is unreachable, got %v, %v", ok, err)
+	}
+
+	reqCtx, reqCancel := context.WithCancel(context.Background())
+	reqCancel()
+	if err := base.WithContext(reqCtx).NewLock("test:lock", LockOptions{}).Lock(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Lock should honor the client context, got %v", err)
+	}
+}
+
+func TestLock_MutualExclusion(t *testing.T) {
+	client, err := getTestRedisClient()
+	if err != nil {
+		t.Skipf("Redis server not available: %v", err)
+		return
+	}
+	defer client.Close()
+	client.Del("test:lock:mutex")
+
+	var (
+		wg      sync.WaitGroup
+		running atomic.Int32
+		total   atomic.Int32
+	)
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock := client.NewLock("test:lock:mutex", LockOptions{TTL: time.Second, RetryInterval: 10 * time.Millisecond})
+			if err := lock.Lock(); err != nil {
+				t.Errorf("Lock failed: %v", err)
+				return
+			}
+			if running.Add(1) != 1 {
+				t.Error("lock held by more than one owner")
+			}
+			time.Sleep(20 * time.Millisecond)
+			running.Add(-1)
+			total.Add(1)
+			if err := lock.Unlock(); err != nil {
+				t.Errorf("Unlock failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if total.Load() != 5 {
+		t.Errorf("Expected 5 critical sections, got %d", total.Load())
+	}
+}
+
+func TestLock_TryLockAndTimeout(t *testing.T) {
+	client, err := getTestRedisClient()
+	if err != nil {
+		t.Skipf("Redis server not available: %v", err)
+		return
+	}
+	defer client.Close()
+	client.Del("test:lock:try")
+
+	owner := client.NewLock("test:lock:try", LockOptions{})
+	if ok, err := owner.TryLock(); !ok || err != nil {
+		t.Fatalf("TryLock failed: %v, %v", ok, err)
+	}
+	defer owner.Unlock()
+
+	other := client.NewLock("test:lock:try", LockOptions{})
+	if ok, _ := other.TryLock(); ok {
+		t.Error("lock should be exclusive")
+	}
+	if err := other.Unlock(); !errors.Is(err, ErrLockNotHeld) {
+		t.Errorf("non-owner unlock should fail, got %v", err)
+	}
+	if ok, _ := owner.TryLock(); ok {
+		t.Error("non-reentrant lock should not be acquired twice")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = client.WithContext(ctx).NewLock("test:lock:try", LockOptions{RetryInterval: 10 * time.Millisecond}).Lock()
+	if !errors.Is(err, ErrLockNotObtained) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Lock should time out, got %v", err)
+	}
+}
+
+func TestLock_Reentrant(t *testing.T) {
+	client, err := getTestRedisClient()
+	if err != nil {
+		t.Skipf("Redis server not available: %v", err)
+		return
+	}
+	defer client.Close()
+	client.Del("test:lock:reentrant")
+
+	lock := client.NewLock("test:lock:reentrant", LockOptions{Reentrant: true})
+	for range 2 {
+		if ok, err := lock.TryLock(); !ok || err != nil {
+			t.Fatalf("reentrant TryLock failed: %v, %v", ok, err)
+		}
+	}
+	if err := lock.Unlock(); err != nil || !lock.Held() {
+		t.Errorf("lock should still be held after first unlock: %v", err)
+	}
+	if n, _ := client.Exists("test:lock:reentrant"); n != 1 {
+		t.Error("lock key should remain until the last unlock")
+	}
+	if err := lock.Unlock(); err != nil || lock.Held() {
+		t.Errorf("lock should be released: %v", err)
+	}
+	if n, _ := client.Exists("test:lock:reentrant"); n != 0 {
+		t.Error("lock key should be deleted")
+	}
+}
+
+func TestLock_WatchdogAndLost(t *testing.T) {
+	client, err := getTestRedisClient()
+	if err != nil {
+		t.Skipf("Redis server not available: %v", err)
+		return
+	}
+	defer client.Close()
+	client.Del("test:lock:watchdog")
+
+	lock := client.NewLock("test:lock:watchdog", LockOptions{TTL: 300 * time.Millisecond})
+	if err := lock.Lock(); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+
+	// 持有时间超过 TTL，看门狗应持续续期
+	time.Sleep(time.Second)
+	if n, _ := client.Exists("test:lock:watchdog"); n != 1 {
+		t.Fatal("watchdog should keep the lock alive")
+	}
+
+	client.Del("test:lock:watchdog")
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("Lost should be closed after the lock is deleted")
+	}
+	if lock.Held() {
+		t.Error("lost lock should not be held")
+	}
+	if err := lock.Unlock(); !errors.Is(err, ErrLockNotHeld) {
+		t.Errorf("unlock after losing the lock should fail, got %v", err)
+	}
+}
@@ -0,0 +1,340 @@
+package redis
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrInvalidationFailed Redis 写入或删除已成功，仅失效广播发布失败。
+// 其他节点的本地缓存不会被淘汰，最多陈旧 TwoLevelConfig.TTL 后自然过期；调用方可重试 Invalidate
+var ErrInvalidationFailed = errors.New("redis: write succeeded but invalidation broadcast failed")
+
+// TwoLevelConfig 二级缓存配置
+type TwoLevelConfig struct {
+	Size    int           // 本地缓存最大条目数，默认 10000，超出时淘汰最久未使用的条目
+	TTL     time.Duration // 本地缓存有效期，默认 1 分钟；失效广播丢失时本地数据最多陈旧该时长
+	Channel string        // 失效广播频道，默认 "cache:invalidate"，共享同一批键的节点须使用相同频道
+}
+
+// invalidation 失效广播消息
+type invalidation struct {
+	Node string   `json:"node"`
+	Keys []string `json:"keys"`
+}
+
+// TwoLevelCache 二级缓存：进程内 LRU 缓存在前，Redis 在后。
+// 通过本缓存写入或删除时经 Publish 广播失效消息，各节点收到后淘汰本地条目。
+// Redis 中的值为普通 JSON，与 SetJSON / GetJSON 兼容
+type TwoLevelCache struct {
+	client *RedisClient
+	config TwoLevelConfig
+	node   string // 本节点标识，忽略自身发出的失效消息
+	local  *lruCache
+	pubsub *redis.PubSub
+	cancel context.CancelFunc
+	done   chan struct{}
+	view   bool // 由 WithContext 创建的视图
+}
+
+// NewTwoLevelCache 创建二级缓存并订阅失效广播，订阅失败时返回错误。
+// 订阅随客户端关闭而结束，应在客户端而非 WithContext 视图上创建
+func (r *RedisClient) NewTwoLevelCache(config TwoLevelConfig) (*TwoLevelCache, error) {
+	if config.Size <= 0 {
+		config.Size = 10000
+	}
+	if config.TTL <= 0 {
+		config.TTL = time.Minute
+	}
+	if config.Channel == "" {
+		config.Channel = "cache:invalidate"
+	}
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	pubsub := r.Subscribe(config.Channel)
+	// 等待订阅确认，确保返回后不会漏掉失效消息
+	if _, err := pubsub.Receive(ctx); err != nil {
+		cancel()
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe %s: %w", config.Channel, err)
+	}
+
+	c := &TwoLevelCache{
+		client: r,
+		config: config,
+		node:   newToken(),
+		local:  newLRUCache(config.Size),
+		pubsub: pubsub,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go c.listen(ctx)
+	return c, nil
+}
+
+// WithContext 返回使用 ctx 访问 Redis 的视图，与原缓存共享本地缓存与订阅；视图的 Close 为空操作
+func (c *TwoLevelCache) WithContext(ctx context.Context) *TwoLevelCache {
+	view := *c
+	view.client = c.client.WithContext(ctx)
+	view.view = true
+	return &view
+}
+
+// Get 读取 JSON 值到 dest：先查本地缓存，未命中时读取 Redis 并写入本地。键不存在时返回 redis.Nil。
+// 本地缓存保存编码后的数据，每次 Get 都解码出独立的值，调用方可放心修改
+func (c *TwoLevelCache) Get(key string, dest interface{}) error {
+	if data, ok := c.local.get(key); ok {
+		return json.Unmarshal(data, dest)
+	}
+
+	generation := c.local.generation(key)
+	data, err := c.client.client.Get(c.client.ctx, key).Bytes()
+	if err == redis.Nil {
+		return redis.Nil
+	}
+	if err != nil {
+		return c.client.handleError("TwoLevelCache.Get", err)
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		return err
+	}
+	// 读取期间该键（所在分片）发生过失效时不写入本地，以免覆盖为旧值
+	c.local.setIf(key, data, c.config.TTL, generation)
+	return nil
+}
+
+// Set 以 JSON 写入 Redis 与本地缓存，并广播失效消息使其他节点淘汰本地条目。
+// 广播失败时返回包装了 ErrInvalidationFailed 的错误，此时 Redis 与本节点已是新值，其他节点最多陈旧 TTL
+func (c *TwoLevelCache) Set(key string, value interface{}, expiration time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	if err := c.client.Set(key, data, expiration); err != nil {
+		c.local.remove(key)
+		return err
+	}
+	ttl := c.config.TTL
+	if expiration > 0 && expiration < ttl {
+		ttl = expiration
+	}
+	c.local.set(key, data, ttl)
+	return c.broadcast(key)
+}
+
+// Delete 删除 Redis 与本地缓存中的键，并广播失效消息；广播失败时返回包装了 ErrInvalidationFailed 的错误
+func (c *TwoLevelCache) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := c.client.Del(keys...)
+	// 删除后再淘汰本地条目，使删除期间读到旧值的回填失效
+	c.local.remove(keys...)
+	if err != nil {
+		return err
+	}
+	return c.broadcast(keys...)
+}
+
+// Invalidate 只淘汰各节点的本地条目，不修改 Redis，用于数据已由其他途径写入 Redis 的场景
+func (c *TwoLevelCache) Invalidate(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	c.local.remove(keys...)
+	return c.broadcast(keys...)
+}
+
+// Len 本地缓存条目数（含已过期未清理的条目）
+func (c *TwoLevelCache) Len() int {
+	return c.local.len()
+}
+
+// Close 取消订阅并清空本地缓存，不关闭 Redis 客户端
+func (c *TwoLevelCache) Close() error {
+	if c.view {
+		return nil
+	}
+	c.cancel()
+	err := c.pubsub.Close()
+	<-c.done
+	c.local.clear()
+	return err
+}
+
+// broadcast 发布失效消息，失败时返回包装了 ErrInvalidationFailed 的错误
+func (c *TwoLevelCache) broadcast(keys ...string) error {
+	message, err := json.Marshal(invalidation{Node: c.node, Keys: keys})
+	if err != nil {
+		return fmt.Errorf("%w: marshal JSON: %w", ErrInvalidationFailed, err)
+	}
+	if err := c.client.Publish(c.config.Channel, message); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidationFailed, c.client.handleError("Publish", err))
+	}
+	return nil
+}
+
+// listen 接收失效消息。断线重连后重新订阅期间可能漏掉消息，收到新的订阅确认时清空本地缓存
+func (c *TwoLevelCache) listen(ctx context.Context) {
+	defer close(c.done)
+	for {
+		msg, err := c.pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.client.handleError("TwoLevelCache.Receive", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			if msg.Kind == "subscribe" {
+				c.local.clear()
+			}
+		case *redis.Message:
+			c.handle(msg.Payload)
+		}
+	}
+}
+
+// handle 处理失效消息，忽略本节点发出的消息
+func (c *TwoLevelCache) handle(payload string) {
+	var message invalidation
+	if err := json.Unmarshal([]byte(payload), &message); err != nil {
+		c.client.handleError("TwoLevelCache.Invalidate", fmt.Errorf("invalid message: %w", err))
+		return
+	}
+	if message.Node == c.node {
+		return
+	}
+	c.local.remove(message.Keys...)
+}
+
+// =============================================================================
+// 本地 LRU 缓存
+// =============================================================================
+
+type lruEntry struct {
+	key     string
+	data    []byte
+	expires time.Time
+}
+
+// lruGenShards 版本号分片数。版本号按键哈希分片，其他键的写入只影响同一分片的回填
+const lruGenShards = 256
+
+// lruCache 带过期时间的 LRU 缓存，过期条目在访问时惰性清理
+type lruCache struct {
+	mutex sync.Mutex
+	size  int
+	list  *list.List
+	items map[string]*list.Element
+	seed  maphash.Seed
+	gens  [lruGenShards]uint64 // 分片内每次写入或淘汰加一，用于丢弃读取期间已失效的回填
+}
+
+func newLRUCache(size int) *lruCache {
+	return &lruCache{size: size, list: list.New(), items: make(map[string]*list.Element), seed: maphash.MakeSeed()}
+}
+
+// shard 键所在的版本号分片
+func (l *lruCache) shard(key string) int {
+	return int(maphash.String(l.seed, key) % lruGenShards)
+}
+
+func (l *lruCache) get(key string) ([]byte, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	elem, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if time.Now().After(entry.expires) {
+		l.list.Remove(elem)
+		delete(l.items, key)
+		return nil, false
+	}
+	l.list.MoveToFront(elem)
+	return entry.data, true
+}
+
+// generation 键所在分片的当前版本号，回填前获取并传给 setIf
+func (l *lruCache) generation(key string) uint64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.gens[l.shard(key)]
+}
+
+// set 写入条目，同时使读取期间的回填失效，避免覆盖为旧值
+func (l *lruCache) set(key string, data []byte, ttl time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.gens[l.shard(key)]++
+	l.store(key, data, ttl)
+}
+
+// setIf 自 generation 之后键所在分片未发生过写入或淘汰时才写入
+func (l *lruCache) setIf(key string, data []byte, ttl time.Duration, generation uint64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.gens[l.shard(key)] == generation {
+		l.store(key, data, ttl)
+	}
+}
+
+func (l *lruCache) store(key string, data []byte, ttl time.Duration) {
+	expires := time.Now().Add(ttl)
+	if elem, ok := l.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.data, entry.expires = data, expires
+		l.list.MoveToFront(elem)
+		return
+	}
+	l.items[key] = l.list.PushFront(&lruEntry{key: key, data: data, expires: expires})
+	for l.list.Len() > l.size {
+		oldest := l.list.Back()
+		l.list.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (l *lruCache) remove(keys ...string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, key := range keys {
+		l.gens[l.shard(key)]++
+		if elem, ok := l.items[key]; ok {
+			l.list.Remove(elem)
+			delete(l.items, key)
+		}
+	}
+}
+
+func (l *lruCache) clear() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for i := range l.gens {
+		l.gens[i]++
+	}
+	l.list.Init()
+	clear(l.items)
+}
+
+func (l *lruCache) len() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.list.Len()
+}
@@ -0,0 +1,177 @@
+package redis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLRUCache(t *testing.T) {
+	cache := newLRUCache(2)
+	cache.set("a", []byte("1"), time.Minute)
+	cache.set("b", []byte("2"), time.Minute)
+	cache.get("a") // a 变为最近使用
+	cache.set("c", []byte("3"), time.Minute)
+	if _, ok := cache.get("b"); ok {
+		t.Error("least recently used entry should be evicted")
+	}
+	if data, ok := cache.get("a"); !ok || string(data) != "1" {
+		t.Errorf("Expected a=1, got %q, %v", data, ok)
+	}
+	if cache.len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", cache.len())
+	}
+
+	cache.set("d", []byte("4"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := cache.get("d"); ok {
+		t.Error("expired entry should not be returned")
+	}
+
+	generation := cache.generation("a")
+	cache.remove("a")
+	cache.setIf("a", []byte("stale"), time.Minute, generation)
+	if _, ok := cache.get("a"); ok {
+		t.Error("refill started before an invalidation should be dropped")
+	}
+	cache.setIf("a", []byte("5"), time.Minute, cache.generation("a"))
+	if data, _ := cache.get("a"); string(data) != "5" {
+		t.Errorf("Expected a=5, got %q", data)
+	}
+
+	// 其他分片的写入不影响回填
+	other := "b"
+	for i := 0; cache.shard(other) == cache.shard("a"); i++ {
+		other = fmt.Sprintf("b%d", i)
+	}
+	generation = cache.generation("a")
+	cache.remove("a")
+	refill := cache.generation("a")
+	cache.set(other, []byte("6"), time.Minute)
+	cache.setIf("a", []byte("7"), time.Minute, refill)
+	if data, _ := cache.get("a"); string(data) != "7" {
+		t.Errorf("writes to other keys should not drop the refill, got %q", data)
+	}
+	if generation == refill {
+		t.Error("invalidation should bump the generation of its shard")
+	}
+
+	generation = cache.generation("a")
+	cache.clear()
+	cache.setIf("a", []byte("stale"), time.Minute, generation)
+	if _, ok := cache.get("a"); ok {
+		t.Error("clear should drop refills of every key")
+	}
+
+	cache.clear()
+	if cache.len() != 0 {
+		t.Error("clear should remove all entries")
+	}
+}
+
+func TestTwoLevelCache_Handle(t *testing.T) {
+	client := unreachableClient()
+	defer client.Close()
+	cache := &TwoLevelCache{client: client, node: "self", local: newLRUCache(10)}
+	cache.local.set("a", []byte("1"), time.Minute)
+	cache.local.set("b", []byte("2"), time.Minute)
+
+	message := func(node string, keys ...string) string {
+		data, _ := json.Marshal(invalidation{Node: node, Keys: keys})
+		return string(data)
+	}
+	cache.handle(message("self", "a"))
+	if _, ok := cache.local.get("a"); !ok {
+		t.Error("own invalidation should be ignored")
+	}
+	cache.handle(message("other", "a", "missing"))
+	if _, ok := cache.local.get("a"); ok {
+		t.Error("invalidation from other nodes should evict the entry")
+	}
+	cache.handle("not json")
+	if cache.Len() != 1 {
+		t.Errorf("Expected 1 entry, got %d", cache.Len())
+	}
+
+	var value int
+	if err := cache.Get("b", &value); err != nil || value != 2 {
+		t.Errorf("local hit should not touch Redis: %d, %v", value, err)
+	}
+	if err := cache.Get("c", &value); err == nil || errors.Is(err, redis.Nil) {
+		t.Errorf("local miss should surface the Redis error, got %v", err)
+	}
+	if err := cache.broadcast("a"); !errors.Is(err, ErrInvalidationFailed) {
+		t.Errorf("failed broadcast should wrap ErrInvalidationFailed, got %v", err)
+	}
+	if err := cache.Set("d", 4, 0); err == nil || errors.Is(err, ErrInvalidationFailed) {
+		t.Errorf("failed Redis write should not be reported as an invalidation failure, got %v", err)
+	}
+}
+
+func TestTwoLevelCache_Invalidation(t *testing.T) {
+	client, err := getTestRedisClient()
+	if err != nil {
+		t.Skipf("Redis server not available: %v", err)
+		return
+	}
+	defer client.Close()
+	client.Del("test:twolevel:user")
+
+	config := TwoLevelConfig{Channel: "test:twolevel:invalidate"}
+	nodeA, err := client.NewTwoLevelCache(config)
+	if err != nil {
+		t.Fatalf("NewTwoLevelCache failed: %v", err)
+	}
+	defer nodeA.Close()
+	nodeB, err := client.NewTwoLevelCache(config)
+	if err != nil {
+		t.Fatalf("NewTwoLevelCache failed: %v", err)
+	}
+	defer nodeB.Close()
+
+	var user TestUser
+	if err := nodeB.Get("test:twolevel:user", &user); !errors.Is(err, redis.Nil) {
+		t.Errorf("Expected redis.Nil, got %v", err)
+	}
+
+	if err := nodeA.Set("test:twolevel:user", TestUser{ID: 1, Name: "Alice"}, time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := nodeB.Get("test:twolevel:user", &user); err != nil || user.Name != "Alice" {
+		t.Fatalf("Get failed: %+v, %v", user, err)
+	}
+	if nodeB.Len() != 1 {
+		t.Error("value should be cached locally")
+	}
+
+	// Redis 中的值与 GetJSON 兼容
+	if err := client.GetJSON("test:twolevel:user", &user); err != nil || user.Name != "Alice" {
+		t.Errorf("GetJSON failed: %+v, %v", user, err)
+	}
+
+	if err := nodeA.Set("test:twolevel:user", TestUser{ID: 1, Name: "Bob"}, time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for nodeB.Len() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := nodeB.Get("test:twolevel:user", &user); err != nil || user.Name != "Bob" {
+		t.Errorf("stale local entry should be evicted: %+v, %v", user, err)
+	}
+
+	if err := nodeA.Delete("test:twolevel:user"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	deadline = time.Now().Add(time.Second)
+	for nodeB.Len() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := nodeB.Get("test:twolevel:user", &user); !errors.Is(err, redis.Nil) {
+		t.Errorf("deleted key should be evicted on all nodes, got %v", err)
+	}
+}